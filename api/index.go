@@ -1,65 +1,65 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/db"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/handlers"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/routes"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
-	"github.com/joho/godotenv"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/app"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
  
-var app *fiber.App
+// connectDeadline bounds the database retries of a cold start, so a
+// database that is down fails the invocation before the platform's
+// function timeout does.
+const connectDeadline = 8 * time.Second
+
+var (
+	server		*fiber.App
+	provider	*sdktrace.TracerProvider
+)
 
 func init() {
-  	err := godotenv.Load(".env") 
+	cfg, err := config.Load()
 	if err != nil {
-		fmt.Println("Failed to load .env file")
+		panic(fmt.Sprintf("invalid configuration:\n%v", err))
+	}
+	if err := cfg.ValidateServerless(); err != nil {
+		panic(fmt.Sprintf("invalid configuration for Vercel:\n%v", err))
 	}
 
-	db := db.ConnectDB()
-
-	
-	uRepository := repository.NewUserRepository(db)
-	aService 	:= services.NewAuthService(uRepository)
-	aHandler	:= handlers.NewAuthService(aService)
-	
-	pRepository := repository.NewProductRepository(db)
-	pService 	:= services.NewProductService(pRepository, uRepository)
-	pHandler	:= handlers.NewProductHandler(pService)
-	// Initialize Fiber app once
-	app = fiber.New()
-
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     os.Getenv("CLIENT_URL"), // Your Vue development server origin
-		AllowCredentials: true,
-		// AllowHeaders: "Origin, Content-Type, Accept, Authorization", // Good to explicitly allow headers if you send them
-	}))
+	// Initialize Fiber app once per function instance
+	ctx, cancel := context.WithTimeout(context.Background(), connectDeadline)
+	defer cancel()
 
-	routes.RegisterRoutes(app, &routes.RouteConfig{
-		ProductHandler: pHandler,
-		AuthHandler: aHandler,
-	})
+	conn, err := app.Connect(ctx, cfg)
+	if err != nil {
+		panic(err)
+	}
 
-	// Define your Fiber routes here
-	app.Get("/", func(c *fiber.Ctx) error {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Hello from Fiber on Vercel!",
-			"path":    c.Path(),
-			"query":   c.Query("name"),
-		})
-	})
+	// The app never shuts down here, so Handler flushes the spans itself.
+	provider, err = tracing.NewProvider(context.Background(), cfg.Tracing, os.Stdout)
+	if err != nil {
+		panic(err)
+	}
+	conn.TracerProvider = provider
 
-	// Add more routes as needed
+	server, err = app.New(conn)
+	if err != nil {
+		panic(err)
+	}
 }
 
 func Handler(w http.ResponseWriter, r *http.Request) {
-  adaptor.FiberApp(app)(w, r)
-}
\ No newline at end of file
+  adaptor.FiberApp(server)(w, r)
+
+  // The instance may be frozen once the response is sent, so the spans of
+  // the request are exported before returning.
+  provider.ForceFlush(r.Context())
+}
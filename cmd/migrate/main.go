@@ -0,0 +1,145 @@
+// Command migrate manages the database schema.
+//
+//	go run ./cmd/migrate up             apply pending migrations
+//	go run ./cmd/migrate down [steps]   roll back the last steps (default 1)
+//	go run ./cmd/migrate status         list migrations and when they ran
+//	go run ./cmd/migrate drift          compare the GORM models with the schema
+//	go run ./cmd/migrate admin <email>  give the admin role to a user
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/db"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
+)
+
+var errUsage = errors.New("usage: migrate up | down [steps] | status | drift | admin <email>")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, errUsage)
+		os.Exit(2)
+	}
+
+	err := run(context.Background(), os.Args[1], os.Args[2:])
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string, args []string) error {
+	// The arguments are checked before connecting, a typo should not wait
+	// for the database.
+	steps := 1
+	switch command {
+	case "up", "status", "drift":
+	case "down":
+		if len(args) > 0 {
+			var err error
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid steps %q", args[0])
+			}
+		}
+	case "admin":
+		if len(args) != 1 {
+			return errUsage
+		}
+	default:
+		return errUsage
+	}
+
+	// Only the database settings matter here, the JWT and mail settings of
+	// the server may be missing.
+	cfg, err := config.Read()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		return err
+	}
+
+	if cfg.Database.Driver != config.DriverPostgres {
+		return fmt.Errorf("migrations target postgres, the %s driver creates its schema on startup", cfg.Database.Driver)
+	}
+
+	conn, err := db.ConnectDB(ctx, cfg.Database, logging.New(cfg.Log, os.Stderr))
+	if err != nil {
+		return err
+	}
+	defer db.Close(conn)
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		done, err := migrator.Up(ctx)
+		for _, migration := range done {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+
+	case "down":
+		done, err := migrator.Down(ctx, steps)
+		for _, migration := range done {
+			fmt.Printf("rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+
+	case "drift":
+		drift, err := migrator.Drift(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(drift) == 0 {
+			fmt.Println("no drift between models and schema")
+			return nil
+		}
+
+		for _, line := range drift {
+			fmt.Println(line)
+		}
+		return fmt.Errorf("%d difference(s) found", len(drift))
+
+	case "admin":
+		if err := db.PromoteAdmin(conn, args[0]); err != nil {
+			return err
+		}
+		fmt.Println("admin role given to", args[0])
+		return nil
+	}
+
+	return errUsage
+}
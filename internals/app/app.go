@@ -0,0 +1,265 @@
+// Package app builds the Fiber application shared by the standalone server
+// in main.go and the Vercel function in api/index.go.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/db"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/handlers"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/health"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/mail"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/metrics"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/middlewares"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/routes"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/jwt"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/password"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+type Config struct {
+	DB				*gorm.DB
+	Config			*config.Config
+	// Repositories replaces the GORM repositories built from DB, e.g. with
+	// repository.NewMemoryRepositories().
+	Repositories	*repository.Repositories
+	// Logger defaults to one built from Config.Log writing to stdout.
+	Logger			*slog.Logger
+	// TracerProvider defaults to one exporting as Config.Tracing says, shut
+	// down with the app. Tests set one with an in-memory exporter.
+	TracerProvider	trace.TracerProvider
+}
+
+// Connect opens the storage selected by cfg.Database.Driver. ctx stops the
+// retries of a Postgres connection.
+func Connect(ctx context.Context, cfg *config.Config) (Config, error) {
+	logger := logging.New(cfg.Log, os.Stdout)
+	conn := Config{Config: cfg, Logger: logger}
+
+	var err error
+	switch cfg.Database.Driver {
+	case config.DriverMemory:
+		conn.Repositories = repository.NewMemoryRepositories()
+	case config.DriverSQLite:
+		conn.DB, err = db.ConnectSQLite(cfg.Database, logger)
+	default:
+		conn.DB, err = db.ConnectDB(ctx, cfg.Database, logger)
+	}
+
+	return conn, err
+}
+
+// Close closes the connection pool opened by Connect, if any.
+func (c Config) Close() error {
+	if c.DB == nil {
+		return nil
+	}
+	return db.Close(c.DB)
+}
+
+type options struct {
+	middlewares	[]fiber.Handler
+	routes		[]func(app *fiber.App)
+	checks		map[string]health.Check
+}
+
+type Option func(*options)
+
+// WithMiddleware adds handlers that run before every route.
+func WithMiddleware(handlers ...fiber.Handler) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, handlers...)
+	}
+}
+
+// WithRoutes registers extra routes after the API routes.
+func WithRoutes(register func(app *fiber.App)) Option {
+	return func(o *options) {
+		o.routes = append(o.routes, register)
+	}
+}
+
+// WithCheck adds a readiness check reported by /readyz under name.
+func WithCheck(name string, check health.Check) Option {
+	return func(o *options) {
+		if o.checks == nil {
+			o.checks = map[string]health.Check{}
+		}
+		o.checks[name] = check
+	}
+}
+
+func New(cfg Config, opts ...Option) (*fiber.App, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	tokens, err := jwt.NewManager(cfg.Config.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("load signing keys: %w", err)
+	}
+
+	policy, err := password.NewPolicy(cfg.Config.Password)
+	if err != nil {
+		return nil, fmt.Errorf("load password policy: %w", err)
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.New(cfg.Config.Log, os.Stdout)
+	}
+
+	var shutdownTracing func(context.Context) error
+	provider := cfg.TracerProvider
+	if provider == nil {
+		sdkProvider, err := tracing.NewProvider(context.Background(), cfg.Config.Tracing, os.Stdout)
+		if err != nil {
+			return nil, fmt.Errorf("set up tracing: %w", err)
+		}
+		provider, shutdownTracing = sdkProvider, sdkProvider.Shutdown
+	}
+	tracer := tracing.Tracer(provider)
+
+	if cfg.DB != nil {
+		if err := tracing.InstrumentDB(cfg.DB, provider); err != nil {
+			return nil, fmt.Errorf("trace database: %w", err)
+		}
+	}
+
+	repos := cfg.Repositories
+	if repos == nil {
+		repos = repository.NewGormRepositories(cfg.DB)
+	}
+
+	var m *metrics.Metrics
+	var mHandler *handlers.MetricsHandler
+	if cfg.Config.Metrics.Enabled {
+		m = metrics.New()
+		mHandler = handlers.NewMetricsHandler(m)
+		if cfg.DB != nil {
+			if err := m.InstrumentDB(cfg.DB, cfg.Config.Database.Driver); err != nil {
+				return nil, fmt.Errorf("instrument database: %w", err)
+			}
+		}
+	}
+
+	limits := repos.RateLimits
+	if cfg.Config.RateLimit.Store == config.RateLimitMemory {
+		limits = repository.NewMemoryRateLimitRepository()
+	}
+
+	aService 	:= services.NewAuthService(repos, tokens, mail.NewSender(cfg.Config.Mail, logger), policy, cfg.Config, logger, m, tracer)
+	aHandler	:= handlers.NewAuthService(aService, cfg.Config)
+
+	pService 	:= services.NewProductService(repos.Products, repos.Users, cfg.Config.RequireVerifiedEmail, logger, tracer)
+	pHandler	:= handlers.NewProductHandler(pService)
+
+	kHandler	:= handlers.NewKeysHandler(tokens)
+
+	checks := health.NewRegistry(health.DefaultTimeout)
+	checks.Register("signing_keys", health.SigningKeys(tokens))
+	if cfg.DB != nil {
+		checks.Register("database", health.Database(cfg.DB))
+	}
+	if cfg.DB != nil && cfg.Config.Database.Driver == config.DriverPostgres {
+		// SQLite creates its schema from the models, not the migrations.
+		migrator, err := db.NewMigrator(cfg.DB)
+		if err != nil {
+			return nil, fmt.Errorf("load migrations: %w", err)
+		}
+		checks.Register("migrations", health.Migrations(migrator))
+	}
+	for name, check := range o.checks {
+		checks.Register(name, check)
+	}
+	hHandler	:= handlers.NewHealthHandler(checks, logger)
+
+	akService	:= services.NewAPIKeyService(repos.APIKeys, repos.Users, logger, tracer)
+	akHandler	:= handlers.NewAPIKeyHandler(akService)
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: handlers.ErrorHandler(logger),
+		ProxyHeader: cfg.Config.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies: cfg.Config.TrustedProxies,
+		EnableIPValidation: true,
+	})
+
+	if shutdownTracing != nil {
+		app.Hooks().OnShutdown(func() error {
+			return shutdownTracing(context.Background())
+		})
+	}
+
+	app.Use(middlewares.Tracing(provider))
+	if m != nil {
+		app.Use(middlewares.Metrics(m))
+	}
+	app.Use(middlewares.RequestLogger(logger))
+
+	// CORS turns an empty origin into a wildcard, which it refuses together
+	// with credentials.
+	if cfg.Config.ClientURL != "" {
+		app.Use(cors.New(cors.Config{
+			AllowOrigins:     cfg.Config.ClientURL, // Your Vue development server origin
+			AllowCredentials: true,
+			// AllowHeaders: "Origin, Content-Type, Accept, Authorization", // Good to explicitly allow headers if you send them
+		}))
+	}
+
+	for _, middleware := range o.middlewares {
+		app.Use(middleware)
+	}
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Hello from Fiber on Vercel!",
+			"path":    c.Path(),
+			"query":   c.Query("name"),
+		})
+	})
+
+	routes.RegisterRoutes(app, &routes.RouteConfig{
+		ProductHandler: pHandler,
+		AuthHandler: aHandler,
+		KeysHandler: kHandler,
+		HealthHandler: hHandler,
+		APIKeyHandler: akHandler,
+		MetricsHandler: mHandler,
+		MetricsAuth: middlewares.MetricsToken(cfg.Config.Metrics.Token),
+		Authenticate: middlewares.Authenticate(tokens, akService),
+		AuthenticateSession: middlewares.JWTProtected(tokens),
+		IPRateLimit: rateLimit(cfg.Config.RateLimit, limits, "ip", cfg.Config.RateLimit.IP, logger),
+		AuthRateLimit: rateLimit(cfg.Config.RateLimit, limits, "auth", cfg.Config.RateLimit.Auth, logger),
+		APIRateLimit: rateLimit(cfg.Config.RateLimit, limits, "api", cfg.Config.RateLimit.API, logger),
+	})
+
+	for _, register := range o.routes {
+		register(app)
+	}
+
+	return app, nil
+}
+
+// rateLimit returns the middleware of one route group, or one that lets
+// every request through when rate limiting is disabled.
+func rateLimit(cfg config.RateLimitConfig, store repository.RateLimitRepository, name string, policy config.RateLimitPolicy, logger *slog.Logger) fiber.Handler {
+	if !cfg.Enabled {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+	return middlewares.RateLimit(store, name, policy, logger)
+}
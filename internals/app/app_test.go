@@ -0,0 +1,619 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testPassword passes the default password policy.
+const testPassword = "kopi-susu-gula-aren"
+
+func testConfig(driver string) *config.Config {
+	cfg := config.Default()
+	cfg.ClientURL = "http://localhost:5173"
+	cfg.BcryptCost = 4
+	cfg.Database.Driver = driver
+	cfg.Database.Path = ":memory:"
+	cfg.JWT.Secret = "test-access-secret-with-32-characters"
+	cfg.JWT.RefreshSecret = "test-refresh-secret-with-32-characters"
+	cfg.Log.Level = "error"
+	return cfg
+}
+
+// connect opens the storage of cfg and closes it when the test ends.
+func connect(t *testing.T, cfg *config.Config) Config {
+	conn, err := Connect(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+type client struct {
+	t		*testing.T
+	app		*fiber.App
+	token	string
+	apiKey	string
+}
+
+func (c *client) do(method, path string, body any) (int, map[string]any) {
+	var reader io.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.app.Test(req, -1)
+	require.NoError(c.t, err)
+
+	var decoded map[string]any
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+func TestApp_ProductFlow(t *testing.T) {
+	for _, driver := range []string{config.DriverMemory, config.DriverSQLite} {
+		t.Run(driver, func(t *testing.T) {
+			server, err := New(connect(t, testConfig(driver)))
+			require.NoError(t, err)
+			c := &client{t: t, app: server}
+
+			status, _ := c.do(http.MethodPost, "/api/auth/register", map[string]any{
+				"name": "Taufik", "email": "taufik@dev.com", "password": testPassword, "confPassword": testPassword,
+			})
+			require.Equal(t, http.StatusCreated, status)
+
+			status, body := c.do(http.MethodPost, "/api/auth/login", map[string]any{
+				"email": "taufik@dev.com", "password": testPassword,
+			})
+			require.Equal(t, http.StatusOK, status)
+			c.token = body["data"].(map[string]any)["accessToken"].(string)
+
+			for _, product := range []map[string]any{
+				{"name": "Produk A", "price": 3000},
+				{"name": "Produk B", "price": 1000},
+				{"name": "Produk C", "price": 2000},
+			} {
+				status, _ = c.do(http.MethodPost, "/api/products", product)
+				require.Equal(t, http.StatusCreated, status)
+			}
+
+			status, body = c.do(http.MethodGet, "/api/products?sort=price&order=asc&limit=2", nil)
+			require.Equal(t, http.StatusOK, status)
+			meta := body["meta"].(map[string]any)
+			assert.Equal(t, float64(3), meta["total"])
+			assert.Len(t, body["data"], 2)
+
+			status, body = c.do(http.MethodGet, "/api/products?sort=price&order=asc&limit=2&cursor="+meta["nextCursor"].(string), nil)
+			require.Equal(t, http.StatusOK, status)
+			data := body["data"].([]any)
+			require.Len(t, data, 1)
+			assert.Equal(t, "Produk A", data[0].(map[string]any)["name"])
+
+			id := data[0].(map[string]any)["id"].(string)
+			status, body = c.do(http.MethodPatch, "/api/products/"+id, map[string]any{"price": 3500})
+			require.Equal(t, http.StatusOK, status)
+			assert.Equal(t, 3500.0, body["data"].(map[string]any)["price"])
+
+			status, _ = c.do(http.MethodDelete, "/api/products/"+id, nil)
+			assert.Equal(t, http.StatusNoContent, status)
+
+			status, body = c.do(http.MethodGet, "/api/products/"+id, nil)
+			assert.Equal(t, http.StatusNotFound, status)
+			assert.Equal(t, "product_not_found", body["code"])
+		})
+	}
+}
+
+func TestApp_RefreshCookie(t *testing.T) {
+	cfg := testConfig(config.DriverMemory)
+	server, err := New(connect(t, cfg))
+	require.NoError(t, err)
+	c := &client{t: t, app: server}
+
+	status, _ := c.do(http.MethodPost, "/api/auth/register", map[string]any{
+		"name": "Taufik", "email": "taufik@dev.com", "password": testPassword, "confPassword": testPassword,
+	})
+	require.Equal(t, http.StatusCreated, status)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"email":"taufik@dev.com","password":"`+testPassword+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var cookie *http.Cookie
+	for _, candidate := range resp.Cookies() {
+		if candidate.Name == "refreshToken" {
+			cookie = candidate
+		}
+	}
+	require.NotNil(t, cookie)
+	assert.WithinDuration(t, time.Now().Add(cfg.JWT.RefreshTTL), cookie.Expires, time.Minute)
+
+	// Malformed, forged and access tokens are client errors, not 500s.
+	for _, token := range []string{"abcde", cookie.Value + "x", decodeBody(t, resp)["data"].(map[string]any)["accessToken"].(string)} {
+		req = httptest.NewRequest(http.MethodPost, "/api/auth/refresh", nil)
+		req.AddCookie(&http.Cookie{Name: "refreshToken", Value: token})
+		resp, err = server.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, "invalid_refresh_token", decodeBody(t, resp)["code"])
+	}
+}
+
+// decodeBody decodes the JSON body of resp.
+func decodeBody(t *testing.T, resp *http.Response) map[string]any {
+	var decoded map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	return decoded
+}
+
+func TestApp_WithoutClientURL(t *testing.T) {
+	cfg := testConfig(config.DriverMemory)
+	cfg.ClientURL = ""
+	server, err := New(connect(t, cfg))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	resp, err := server.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestApp_APIKeyFlow(t *testing.T) {
+	for _, driver := range []string{config.DriverMemory, config.DriverSQLite} {
+		t.Run(driver, func(t *testing.T) {
+			server, err := New(connect(t, testConfig(driver)))
+			require.NoError(t, err)
+			c := &client{t: t, app: server}
+
+			status, _ := c.do(http.MethodPost, "/api/auth/register", map[string]any{
+				"name": "Taufik", "email": "taufik@dev.com", "password": testPassword, "confPassword": testPassword,
+			})
+			require.Equal(t, http.StatusCreated, status)
+
+			status, body := c.do(http.MethodPost, "/api/auth/login", map[string]any{
+				"email": "taufik@dev.com", "password": testPassword,
+			})
+			require.Equal(t, http.StatusOK, status)
+			c.token = body["data"].(map[string]any)["accessToken"].(string)
+
+			status, body = c.do(http.MethodPost, "/api/auth/api-keys", map[string]any{
+				"name": "ci", "scopes": []string{"products:write"},
+			})
+			require.Equal(t, http.StatusCreated, status)
+			created := body["data"].(map[string]any)
+			key := created["key"].(string)
+
+			status, body = c.do(http.MethodGet, "/api/auth/api-keys", nil)
+			require.Equal(t, http.StatusOK, status)
+			listed := body["data"].([]any)
+			require.Len(t, listed, 1)
+			assert.NotContains(t, listed[0], "key")
+
+			c.token, c.apiKey = "", key
+
+			status, _ = c.do(http.MethodPost, "/api/products", map[string]any{"name": "Produk A", "price": 1000})
+			assert.Equal(t, http.StatusCreated, status)
+
+			// The key only has products:write.
+			status, _ = c.do(http.MethodGet, "/api/products", nil)
+			assert.Equal(t, http.StatusForbidden, status)
+
+			// Keys can not manage keys.
+			status, _ = c.do(http.MethodGet, "/api/auth/api-keys", nil)
+			assert.Equal(t, http.StatusUnauthorized, status)
+
+			c.apiKey = "sk_invalid"
+			status, body = c.do(http.MethodPost, "/api/products", map[string]any{"name": "Produk B", "price": 1000})
+			assert.Equal(t, http.StatusUnauthorized, status)
+			assert.Equal(t, "invalid_api_key", body["code"])
+		})
+	}
+}
+
+func TestApp_LoginLockout(t *testing.T) {
+	for _, driver := range []string{config.DriverMemory, config.DriverSQLite} {
+		t.Run(driver, func(t *testing.T) {
+			cfg := connect(t, testConfig(driver))
+			cfg.Config.Lockout.MaxAttempts = 2
+			if cfg.Repositories == nil {
+				cfg.Repositories = repository.NewGormRepositories(cfg.DB)
+			}
+			server, err := New(cfg)
+			require.NoError(t, err)
+			c := &client{t: t, app: server}
+
+			for _, email := range []string{"taufik@dev.com", "admin@dev.com"} {
+				status, _ := c.do(http.MethodPost, "/api/auth/register", map[string]any{
+					"name": "Taufik", "email": email, "password": testPassword, "confPassword": testPassword,
+				})
+				require.Equal(t, http.StatusCreated, status)
+			}
+
+			ctx := context.Background()
+			admin, err := cfg.Repositories.Users.FindByEmail(ctx, "admin@dev.com")
+			require.NoError(t, err)
+			roles, err := cfg.Repositories.Roles.FindByNames(ctx, []string{models.RoleAdmin})
+			require.NoError(t, err)
+			require.NoError(t, cfg.Repositories.Users.SetRoles(ctx, admin, roles))
+
+			// Unknown emails and wrong passwords get the same answer.
+			status, unknown := c.do(http.MethodPost, "/api/auth/login", map[string]any{
+				"email": "nobody@dev.com", "password": testPassword,
+			})
+			assert.Equal(t, http.StatusUnauthorized, status)
+
+			for i := 0; i < 2; i++ {
+				status, body := c.do(http.MethodPost, "/api/auth/login", map[string]any{
+					"email": "taufik@dev.com", "password": "wrong-password",
+				})
+				assert.Equal(t, http.StatusUnauthorized, status)
+				assert.Equal(t, unknown["code"], body["code"])
+			}
+
+			status, body := c.do(http.MethodPost, "/api/auth/login", map[string]any{
+				"email": "taufik@dev.com", "password": testPassword,
+			})
+			assert.Equal(t, http.StatusTooManyRequests, status)
+			assert.Equal(t, "too_many_attempts", body["code"])
+
+			status, body = c.do(http.MethodPost, "/api/auth/login", map[string]any{
+				"email": "admin@dev.com", "password": testPassword,
+			})
+			require.Equal(t, http.StatusOK, status)
+			c.token = body["data"].(map[string]any)["accessToken"].(string)
+
+			user, err := cfg.Repositories.Users.FindByEmail(ctx, "taufik@dev.com")
+			require.NoError(t, err)
+			status, _ = c.do(http.MethodPost, "/api/auth/users/"+user.ID.String()+"/unlock", nil)
+			require.Equal(t, http.StatusNoContent, status)
+
+			c.token = ""
+			status, _ = c.do(http.MethodPost, "/api/auth/login", map[string]any{
+				"email": "taufik@dev.com", "password": testPassword,
+			})
+			assert.Equal(t, http.StatusOK, status)
+		})
+	}
+}
+
+func TestApp_RateLimit(t *testing.T) {
+	for _, driver := range []string{config.DriverMemory, config.DriverSQLite} {
+		t.Run(driver, func(t *testing.T) {
+			cfg := testConfig(driver)
+			cfg.RateLimit.Auth.Limit = 2
+			if driver == config.DriverSQLite {
+				cfg.RateLimit.Store = config.RateLimitDatabase
+			}
+			server, err := New(connect(t, cfg))
+			require.NoError(t, err)
+
+			login := func() *http.Response {
+				req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"email":"nobody@dev.com","password":"1234567890"}`))
+				req.Header.Set("Content-Type", "application/json")
+				resp, err := server.Test(req, -1)
+				require.NoError(t, err)
+				return resp
+			}
+
+			for remaining := 1; remaining >= 0; remaining-- {
+				resp := login()
+				assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+				assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+				assert.Equal(t, strconv.Itoa(remaining), resp.Header.Get("RateLimit-Remaining"))
+				assert.Equal(t, "2;w=60", resp.Header.Get("RateLimit-Policy"))
+			}
+
+			resp := login()
+			assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+			assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+			assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+		})
+	}
+}
+
+func TestApp_RefreshAndLogoutRateLimited(t *testing.T) {
+	cfg := testConfig(config.DriverMemory)
+	cfg.RateLimit.Auth.Limit = 2
+	server, err := New(connect(t, cfg))
+	require.NoError(t, err)
+	c := &client{t: t, app: server}
+
+	// The cookie is SameSite=None, a cross site GET must not rotate it.
+	status, _ := c.do(http.MethodGet, "/api/auth/refresh", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+
+	for i := 0; i < 2; i++ {
+		status, _ = c.do(http.MethodPost, "/api/auth/refresh", nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	}
+
+	status, _ = c.do(http.MethodPost, "/api/auth/refresh", nil)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	status, _ = c.do(http.MethodPost, "/api/auth/logout", nil)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+}
+
+func TestApp_IPRateLimitBeforeAuthentication(t *testing.T) {
+	cfg := testConfig(config.DriverMemory)
+	cfg.RateLimit.IP.Limit = 2
+	server, err := New(connect(t, cfg))
+	require.NoError(t, err)
+	c := &client{t: t, app: server, apiKey: "sk_invalid"}
+
+	for i := 0; i < 2; i++ {
+		status, body := c.do(http.MethodGet, "/api/products", nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "invalid_api_key", body["code"])
+	}
+
+	// Invalid keys never reach the per user limit, the IP limit stops them.
+	status, body := c.do(http.MethodGet, "/api/products", nil)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, "rate_limited", body["code"])
+
+	c.apiKey = ""
+	status, _ = c.do(http.MethodPost, "/api/auth/refresh", nil)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+}
+
+func TestApp_ProxyHeaderOnlyFromTrustedProxies(t *testing.T) {
+	cfg := testConfig(config.DriverMemory)
+	cfg.RateLimit.IP.Limit = 1
+	cfg.ProxyHeader = "X-Forwarded-For"
+
+	send := func(server *fiber.App, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		resp, err := server.Test(req, -1)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	// The test peer is 0.0.0.0, not a trusted proxy, so the header it sends
+	// is ignored and every request counts against the peer.
+	cfg.TrustedProxies = []string{"10.0.0.0/8"}
+	server, err := New(connect(t, cfg))
+	require.NoError(t, err)
+	assert.NotEqual(t, http.StatusTooManyRequests, send(server, "203.0.113.1"))
+	assert.Equal(t, http.StatusTooManyRequests, send(server, "203.0.113.2"))
+
+	cfg.TrustedProxies = []string{"0.0.0.0"}
+	server, err = New(connect(t, cfg))
+	require.NoError(t, err)
+	assert.NotEqual(t, http.StatusTooManyRequests, send(server, "203.0.113.1"))
+	assert.NotEqual(t, http.StatusTooManyRequests, send(server, "203.0.113.2"))
+}
+
+func TestApp_RequestLogging(t *testing.T) {
+	cfg := testConfig(config.DriverMemory)
+	cfg.Log.Level = "info"
+
+	var logs bytes.Buffer
+	conn := connect(t, cfg)
+	conn.Logger = logging.New(cfg.Log, &logs)
+	server, err := New(conn)
+	require.NoError(t, err)
+
+	send := func(method, path, requestID, token string, body string) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := server.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	credentials := `{"email":"taufik@dev.com","password":"` + testPassword + `"}`
+	resp := send(http.MethodPost, "/api/auth/register", "", "", `{"name":"Taufik","email":"taufik@dev.com","password":"`+testPassword+`","confPassword":"`+testPassword+`"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = send(http.MethodPost, "/api/auth/login", "", "", credentials)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var login struct {
+		Data struct {
+			AccessToken string `json:"accessToken"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&login))
+
+	logs.Reset()
+	resp = send(http.MethodGet, "/api/products/not-a-uuid", "trace-123", login.Data.AccessToken, "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "trace-123", resp.Header.Get("X-Request-ID"))
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	assert.Equal(t, "request", record["msg"])
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "trace-123", record["request_id"])
+	assert.NotEmpty(t, record["user_id"])
+	assert.Equal(t, http.MethodGet, record["method"])
+	assert.Equal(t, "/api/products/:id", record["route"])
+	assert.Equal(t, float64(http.StatusBadRequest), record["status"])
+	assert.Equal(t, "invalid_id", record["error_code"])
+	assert.Contains(t, record, "latency_ms")
+
+	// IDs that could forge log lines or headers are replaced.
+	resp = send(http.MethodPost, "/api/auth/login", "bad id\n", "", credentials)
+	assert.NotEqual(t, "bad id\n", resp.Header.Get("X-Request-ID"))
+	assert.Len(t, resp.Header.Get("X-Request-ID"), 36)
+
+	assert.NotContains(t, logs.String(), testPassword)
+	assert.NotContains(t, logs.String(), login.Data.AccessToken)
+}
+
+func TestApp_Metrics(t *testing.T) {
+	const token = "test-metrics-token-with-32-characters"
+
+	cfg := testConfig(config.DriverSQLite)
+	cfg.Metrics.Enabled = true
+	cfg.Metrics.Token = token
+	server, err := New(connect(t, cfg))
+	require.NoError(t, err)
+	c := &client{t: t, app: server}
+
+	status, _ := c.do(http.MethodPost, "/api/auth/register", map[string]any{
+		"name": "Taufik", "email": "taufik@dev.com", "password": testPassword, "confPassword": testPassword,
+	})
+	require.Equal(t, http.StatusCreated, status)
+
+	status, _ = c.do(http.MethodPost, "/api/auth/login", map[string]any{"email": "taufik@dev.com", "password": "salah-password-123"})
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, _ = c.do(http.MethodPost, "/api/auth/login", map[string]any{"email": "taufik@dev.com", "password": testPassword})
+	require.Equal(t, http.StatusOK, status)
+
+	scrape := func(authorization string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := server.Test(req, -1)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, _ = scrape("")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = scrape("Bearer wrong-token")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, body := scrape("Bearer " + token)
+	require.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `http_requests_total{method="POST",route="/api/auth/login",status="401"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="POST",route="/api/auth/login",status="200"} 1`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="POST",route="/api/auth/register",status="201"} 1`)
+	assert.Contains(t, body, `auth_logins_total{result="failure",step="password"} 1`)
+	assert.Contains(t, body, `auth_logins_total{result="success",step="password"} 1`)
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="query",status="ok",table="users"}`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="sqlite"}`)
+}
+
+func TestApp_MetricsDisabled(t *testing.T) {
+	server, err := New(connect(t, testConfig(config.DriverMemory)))
+	require.NoError(t, err)
+
+	resp, err := server.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestApp_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	conn := connect(t, testConfig(config.DriverSQLite))
+	conn.TracerProvider = provider
+	server, err := New(conn)
+	require.NoError(t, err)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"email":"nobody@dev.com","password":"`+testPassword+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := server.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		assert.Equal(t, traceID, span.SpanContext.TraceID().String(), span.Name)
+		if _, ok := spans[span.Name]; !ok {
+			spans[span.Name] = span
+		}
+	}
+
+	serverSpan, ok := spans["POST /api/auth/login"]
+	require.True(t, ok, "server span")
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent.SpanID().String())
+	assert.Contains(t, serverSpan.Attributes, attribute.Int("http.response.status_code", http.StatusUnauthorized))
+	assert.Contains(t, serverSpan.Attributes, attribute.String("error.type", "invalid_credentials"))
+
+	login, ok := spans["AuthService.Login"]
+	require.True(t, ok, "service span")
+	assert.Equal(t, serverSpan.SpanContext.SpanID(), login.Parent.SpanID())
+	assert.Equal(t, codes.Error, login.Status.Code)
+
+	query, ok := spans["gorm.query"]
+	require.True(t, ok, "query span")
+	assert.Equal(t, login.SpanContext.SpanID(), query.Parent.SpanID())
+	assert.Contains(t, query.Attributes, attribute.String("db.system", "sqlite"))
+}
+
+func TestApp_Health(t *testing.T) {
+	healthy := true
+	var logs bytes.Buffer
+	conn := connect(t, testConfig(config.DriverSQLite))
+	conn.Logger = logging.New(config.LogConfig{Level: "info", Format: config.LogJSON}, &logs)
+	server, err := New(conn, WithCheck("cache", func(ctx context.Context) error {
+		if !healthy {
+			return errors.New("connection refused")
+		}
+		return nil
+	}))
+	require.NoError(t, err)
+	c := &client{t: t, app: server}
+
+	status, body := c.do(http.MethodGet, "/healthz", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "up", body["status"])
+
+	status, body = c.do(http.MethodGet, "/readyz", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "up", body["status"])
+	checks := body["checks"].(map[string]any)
+	assert.Len(t, checks, 3)
+	for _, name := range []string{"database", "signing_keys", "cache"} {
+		assert.Equal(t, "up", checks[name].(map[string]any)["status"], name)
+	}
+
+	healthy = false
+	status, body = c.do(http.MethodGet, "/readyz", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "down", body["status"])
+	cache := body["checks"].(map[string]any)["cache"].(map[string]any)
+	assert.Equal(t, "down", cache["status"])
+
+	// The probe is public, the reason is only logged.
+	assert.NotContains(t, cache, "error")
+	assert.Contains(t, logs.String(), "connection refused")
+}
@@ -0,0 +1,786 @@
+// Package config loads the application settings from an optional YAML or
+// TOML file, the .env file and the environment, in that order of precedence
+// (later sources win), and validates them once at boot.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/crypto"
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// MinSecretLength is the minimum length of the HMAC signing secrets.
+const MinSecretLength = 32
+
+type Config struct {
+	Port					string			`yaml:"port" toml:"port"`
+	// ClientURL is the origin allowed by CORS, e.g. the Vue development server.
+	// When empty no cross origin requests are allowed.
+	ClientURL				string			`yaml:"client_url" toml:"client_url"`
+	// PublicURL is the address the API is reached at, used in emailed links.
+	PublicURL				string			`yaml:"public_url" toml:"public_url"`
+	// ProxyHeader is the header carrying the client IP when the app runs
+	// behind a proxy, e.g. X-Forwarded-For on Vercel. Empty uses the peer.
+	ProxyHeader				string			`yaml:"proxy_header" toml:"proxy_header"`
+	// TrustedProxies are the addresses and CIDR ranges whose ProxyHeader is
+	// believed, any other peer is seen as is. Vercel replaces the header
+	// itself, so 0.0.0.0/0 and ::/0 are fine there.
+	TrustedProxies			[]string		`yaml:"trusted_proxies" toml:"trusted_proxies"`
+	BcryptCost				int				`yaml:"bcrypt_cost" toml:"bcrypt_cost"`
+	// PasswordResetTTL is how long a password reset link stays valid.
+	PasswordResetTTL		time.Duration	`yaml:"password_reset_ttl" toml:"password_reset_ttl"`
+	// EmailVerificationTTL is how long an email verification link stays valid.
+	EmailVerificationTTL	time.Duration	`yaml:"email_verification_ttl" toml:"email_verification_ttl"`
+	// RequireVerifiedEmail stops users from creating products until they
+	// have verified their email address.
+	RequireVerifiedEmail	bool			`yaml:"require_verified_email" toml:"require_verified_email"`
+	// MFAIssuer is the account issuer shown by authenticator apps.
+	MFAIssuer				string			`yaml:"mfa_issuer" toml:"mfa_issuer"`
+	// ShutdownTimeout is how long the standalone server waits for in-flight
+	// requests once it is asked to stop.
+	ShutdownTimeout			time.Duration	`yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+
+	Database				DatabaseConfig	`yaml:"database" toml:"database"`
+	JWT						JWTConfig		`yaml:"jwt" toml:"jwt"`
+	Mail					MailConfig		`yaml:"mail" toml:"mail"`
+	Lockout					LockoutConfig	`yaml:"lockout" toml:"lockout"`
+	RateLimit				RateLimitConfig	`yaml:"rate_limit" toml:"rate_limit"`
+	Password				PasswordConfig	`yaml:"password" toml:"password"`
+	Log						LogConfig		`yaml:"log" toml:"log"`
+	Metrics					MetricsConfig	`yaml:"metrics" toml:"metrics"`
+	Tracing					TracingConfig	`yaml:"tracing" toml:"tracing"`
+}
+
+// Database drivers. SQLite and memory are meant for tests, offline demos and
+// local development without Postgres.
+const (
+	DriverPostgres	= "postgres"
+	DriverSQLite	= "sqlite"
+	DriverMemory	= "memory"
+)
+
+type DatabaseConfig struct {
+	Driver		string	`yaml:"driver" toml:"driver"`
+	// Path is the SQLite database file, ":memory:" keeps it in memory.
+	Path		string	`yaml:"path" toml:"path"`
+
+	Host		string	`yaml:"host" toml:"host"`
+	Port		int		`yaml:"port" toml:"port"`
+	User		string	`yaml:"user" toml:"user"`
+	Password	string	`yaml:"password" toml:"password"`
+	Name		string	`yaml:"name" toml:"name"`
+	SSLMode		string	`yaml:"sslmode" toml:"sslmode"`
+
+	// ConnectTimeout bounds each attempt to reach Postgres. Failed attempts
+	// are retried ConnectAttempts times in all, waiting ConnectBackoff
+	// after the first failure and twice as long after every next one.
+	ConnectTimeout	time.Duration	`yaml:"connect_timeout" toml:"connect_timeout"`
+	ConnectAttempts	int				`yaml:"connect_attempts" toml:"connect_attempts"`
+	ConnectBackoff	time.Duration	`yaml:"connect_backoff" toml:"connect_backoff"`
+}
+
+// Signing algorithms of the access tokens. HS256 needs only JWT_SECRET, the
+// asymmetric ones let other services verify tokens with the published keys.
+const (
+	AlgorithmHS256	= "HS256"
+	AlgorithmRS256	= "RS256"
+	AlgorithmEdDSA	= "EdDSA"
+)
+
+type JWTConfig struct {
+	Algorithm		string			`yaml:"algorithm" toml:"algorithm"`
+	Secret			string			`yaml:"secret" toml:"secret"`
+	RefreshSecret	string			`yaml:"refresh_secret" toml:"refresh_secret"`
+	// KeysDir holds the PEM keys of the asymmetric algorithms, named
+	// <kid>.pem. Public keys stay valid for verification after a rotation.
+	KeysDir			string			`yaml:"keys_dir" toml:"keys_dir"`
+	// ActiveKeyID is the kid of the key that signs new tokens.
+	ActiveKeyID		string			`yaml:"active_key_id" toml:"active_key_id"`
+	// SigningKey is a PEM private key given inline, for platforms without a
+	// file system such as Vercel. It is used with ActiveKeyID as its kid.
+	SigningKey		string			`yaml:"signing_key" toml:"signing_key"`
+	// Issuer and Audience are set as iss and aud of every token and checked
+	// on every token presented.
+	Issuer			string			`yaml:"issuer" toml:"issuer"`
+	Audience		string			`yaml:"audience" toml:"audience"`
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat.
+	Leeway			time.Duration	`yaml:"leeway" toml:"leeway"`
+	AccessTTL		time.Duration	`yaml:"access_ttl" toml:"access_ttl"`
+	RefreshTTL		time.Duration	`yaml:"refresh_ttl" toml:"refresh_ttl"`
+	// MFATTL is how long the token returned by a login waiting for the
+	// second factor stays valid.
+	MFATTL			time.Duration	`yaml:"mfa_ttl" toml:"mfa_ttl"`
+}
+
+// Mail drivers. Log only records that a message was sent, file stores the
+// messages as .eml files in MailConfig.Dir. Neither delivers mail, so there
+// is no default and MAIL_DRIVER must be set explicitly.
+const (
+	MailLog		= "log"
+	MailFile	= "file"
+)
+
+type MailConfig struct {
+	Driver	string	`yaml:"driver" toml:"driver"`
+	Dir		string	`yaml:"dir" toml:"dir"`
+	From	string	`yaml:"from" toml:"from"`
+}
+
+// LockoutConfig throttles password guessing. Once an account or an IP address
+// reaches its number of failed logins, every further failure locks it for
+// BaseDelay, doubled on each failure up to MaxDelay.
+type LockoutConfig struct {
+	MaxAttempts		int				`yaml:"max_attempts" toml:"max_attempts"`
+	// IPMaxAttempts turns on the limit per client IP address. Without
+	// ProxyHeader every client behind the proxy shares the proxy address,
+	// so it needs ProxyHeader. 0 turns it off.
+	IPMaxAttempts	int				`yaml:"ip_max_attempts" toml:"ip_max_attempts"`
+	BaseDelay		time.Duration	`yaml:"base_delay" toml:"base_delay"`
+	MaxDelay		time.Duration	`yaml:"max_delay" toml:"max_delay"`
+	// Window is how long failures are remembered without a new one.
+	Window			time.Duration	`yaml:"window" toml:"window"`
+}
+
+// Password hashing algorithms. Hashes of either algorithm are accepted at
+// login, and rehashed with the configured one.
+const (
+	PasswordArgon2id	= crypto.Argon2id
+	PasswordBcrypt		= crypto.Bcrypt
+)
+
+// PasswordConfig picks how new password hashes are made and which new
+// passwords are accepted. The bcrypt cost is Config.BcryptCost, the Argon2
+// parameters default to the OWASP minimum.
+type PasswordConfig struct {
+	Algorithm			string	`yaml:"algorithm" toml:"algorithm"`
+	// Argon2Memory is in KiB.
+	Argon2Memory		int		`yaml:"argon2_memory" toml:"argon2_memory"`
+	Argon2Iterations	int		`yaml:"argon2_iterations" toml:"argon2_iterations"`
+	Argon2Parallelism	int		`yaml:"argon2_parallelism" toml:"argon2_parallelism"`
+
+	MinLength			int		`yaml:"min_length" toml:"min_length"`
+	// MinEntropy is the least estimated strength of a password, in bits.
+	MinEntropy			int		`yaml:"min_entropy" toml:"min_entropy"`
+	// BreachList is a file of SHA-1 hashes of breached passwords, one per
+	// line. Empty skips the check.
+	BreachList			string	`yaml:"breach_list" toml:"breach_list"`
+}
+
+// Log formats. JSON is meant for log collectors, text for reading in a
+// terminal.
+const (
+	LogJSON	= "json"
+	LogText	= "text"
+)
+
+type LogConfig struct {
+	// Level is the least level logged: debug, info, warn or error. Debug
+	// also logs every SQL statement, without its parameters.
+	Level	string	`yaml:"level" toml:"level"`
+	Format	string	`yaml:"format" toml:"format"`
+}
+
+// MetricsConfig enables the Prometheus /metrics endpoint. Scrapers must send
+// Token as a bearer token.
+type MetricsConfig struct {
+	Enabled	bool	`yaml:"enabled" toml:"enabled"`
+	Token	string	`yaml:"token" toml:"token"`
+}
+
+// Trace exporters. OTLP sends spans over HTTP to a collector, stdout prints
+// them as JSON for local debugging, none records nothing.
+const (
+	TracingNone		= "none"
+	TracingStdout	= "stdout"
+	TracingOTLP		= "otlp"
+)
+
+type TracingConfig struct {
+	Exporter	string	`yaml:"exporter" toml:"exporter"`
+	// Endpoint is the OTLP/HTTP URL, e.g. http://localhost:4318. Empty uses
+	// the OTEL_EXPORTER_OTLP_ENDPOINT variable or the collector default.
+	Endpoint	string	`yaml:"endpoint" toml:"endpoint"`
+	ServiceName	string	`yaml:"service_name" toml:"service_name"`
+}
+
+// Rate limit stores. Memory counts per instance, database shares the counts
+// between every instance. Serverless functions on Vercel are separate
+// instances that come and go, so they need database, see
+// ValidateServerless.
+const (
+	RateLimitMemory		= "memory"
+	RateLimitDatabase	= "database"
+)
+
+// Rate limit algorithms. A token bucket allows bursts of Limit requests and
+// refills at Limit per Window, a sliding window allows Limit requests in any
+// Window.
+const (
+	RateLimitTokenBucket	= "token_bucket"
+	RateLimitSlidingWindow	= "sliding_window"
+)
+
+// What requests are counted by. User and API key fall back to the IP address
+// on requests without them.
+const (
+	RateLimitKeyIP		= "ip"
+	RateLimitKeyUser	= "user"
+	RateLimitKeyAPIKey	= "api_key"
+)
+
+type RateLimitConfig struct {
+	Enabled	bool			`yaml:"enabled" toml:"enabled"`
+	Store	string			`yaml:"store" toml:"store"`
+	// IP throttles every /api request by client address before
+	// authentication, so floods of invalid tokens or API keys are limited
+	// too. Its key is always ip.
+	IP		RateLimitPolicy	`yaml:"ip" toml:"ip"`
+	// Auth throttles login, registration and account recovery.
+	Auth	RateLimitPolicy	`yaml:"auth" toml:"auth"`
+	// API throttles the authenticated routes.
+	API		RateLimitPolicy	`yaml:"api" toml:"api"`
+}
+
+type RateLimitPolicy struct {
+	Algorithm	string			`yaml:"algorithm" toml:"algorithm"`
+	Limit		int				`yaml:"limit" toml:"limit"`
+	Window		time.Duration	`yaml:"window" toml:"window"`
+	Key			string			`yaml:"key" toml:"key"`
+}
+
+// DSN returns the Postgres connection string.
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%v port=%v user=%v password=%v dbname=%v sslmode=%v",
+		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode)
+}
+
+// Default returns the settings used when a source does not set a value.
+func Default() *Config {
+	return &Config{
+		Port: "8080",
+		PublicURL: "http://localhost:8080",
+		BcryptCost: 10,
+		PasswordResetTTL: time.Hour,
+		EmailVerificationTTL: 24 * time.Hour,
+		MFAIssuer: "Fiber App",
+		ShutdownTimeout: 10 * time.Second,
+		Database: DatabaseConfig{
+			Driver: DriverPostgres,
+			Path: "app.db",
+			Port: 5432,
+			SSLMode: "require",
+			ConnectTimeout: 5 * time.Second,
+			ConnectAttempts: 5,
+			ConnectBackoff: 500 * time.Millisecond,
+		},
+		JWT: JWTConfig{
+			Algorithm: AlgorithmHS256,
+			Issuer: "golang-vercel-deployment",
+			Audience: "golang-vercel-deployment",
+			Leeway: 30 * time.Second,
+			AccessTTL: 2 * time.Minute,
+			RefreshTTL: 7 * 24 * time.Hour,
+			MFATTL: 5 * time.Minute,
+		},
+		Mail: MailConfig{
+			Dir: "mail",
+			From: "no-reply@localhost",
+		},
+		Lockout: LockoutConfig{
+			MaxAttempts: 5,
+			BaseDelay: 30 * time.Second,
+			MaxDelay: 15 * time.Minute,
+			Window: time.Hour,
+		},
+		Password: PasswordConfig{
+			Algorithm: PasswordArgon2id,
+			Argon2Memory: 19 * 1024,
+			Argon2Iterations: 2,
+			Argon2Parallelism: 1,
+			MinLength: 8,
+			MinEntropy: 35,
+		},
+		Log: LogConfig{
+			Level: "info",
+			Format: LogJSON,
+		},
+		Tracing: TracingConfig{
+			Exporter: TracingNone,
+			ServiceName: "golang-vercel-deployment",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store: RateLimitMemory,
+			IP: RateLimitPolicy{
+				Algorithm: RateLimitTokenBucket,
+				Limit: 300,
+				Window: time.Minute,
+				Key: RateLimitKeyIP,
+			},
+			Auth: RateLimitPolicy{
+				Algorithm: RateLimitSlidingWindow,
+				Limit: 10,
+				Window: time.Minute,
+				Key: RateLimitKeyIP,
+			},
+			API: RateLimitPolicy{
+				Algorithm: RateLimitTokenBucket,
+				Limit: 120,
+				Window: time.Minute,
+				Key: RateLimitKeyUser,
+			},
+		},
+	}
+}
+
+// Load reads the configuration like Read, then validates the result.
+func Load() (*Config, error) {
+	cfg, err := Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Read reads the configuration file named by CONFIG_FILE (if any), the .env
+// file and the environment without validating them, for tools that need
+// only part of the settings.
+func Read() (*Config, error) {
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, c)
+	case ".toml":
+		err = toml.Unmarshal(content, c)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// envVars maps every environment variable to the field it sets.
+func (c *Config) envVars() map[string]any {
+	return map[string]any{
+		"PORT":							&c.Port,
+		"CLIENT_URL":					&c.ClientURL,
+		"PUBLIC_URL":					&c.PublicURL,
+		"PROXY_HEADER":					&c.ProxyHeader,
+		"TRUSTED_PROXIES":				&c.TrustedProxies,
+		"BCRYPT_COST":					&c.BcryptCost,
+		"PASSWORD_ALGORITHM":			&c.Password.Algorithm,
+		"PASSWORD_ARGON2_MEMORY":		&c.Password.Argon2Memory,
+		"PASSWORD_ARGON2_ITERATIONS":	&c.Password.Argon2Iterations,
+		"PASSWORD_ARGON2_PARALLELISM":	&c.Password.Argon2Parallelism,
+		"PASSWORD_MIN_LENGTH":			&c.Password.MinLength,
+		"PASSWORD_MIN_ENTROPY":			&c.Password.MinEntropy,
+		"PASSWORD_BREACH_LIST":			&c.Password.BreachList,
+		"DB_DRIVER":					&c.Database.Driver,
+		"DB_PATH":						&c.Database.Path,
+		"DB_HOST":						&c.Database.Host,
+		"DB_PORT":						&c.Database.Port,
+		"DB_USER":						&c.Database.User,
+		"DB_PASSWORD":					&c.Database.Password,
+		"DB_NAME":						&c.Database.Name,
+		"DB_SSLMODE":					&c.Database.SSLMode,
+		"DB_CONNECT_TIMEOUT":			&c.Database.ConnectTimeout,
+		"DB_CONNECT_ATTEMPTS":			&c.Database.ConnectAttempts,
+		"DB_CONNECT_BACKOFF":			&c.Database.ConnectBackoff,
+		"JWT_ALGORITHM":				&c.JWT.Algorithm,
+		"JWT_SECRET":					&c.JWT.Secret,
+		"JWT_KEYS_DIR":					&c.JWT.KeysDir,
+		"JWT_ACTIVE_KEY_ID":			&c.JWT.ActiveKeyID,
+		"JWT_SIGNING_KEY":				&c.JWT.SigningKey,
+		"JWT_REFRESH_SECRET":			&c.JWT.RefreshSecret,
+		"JWT_ISSUER":					&c.JWT.Issuer,
+		"JWT_AUDIENCE":					&c.JWT.Audience,
+		"JWT_LEEWAY":					&c.JWT.Leeway,
+		"JWT_ACCESS_TTL":				&c.JWT.AccessTTL,
+		"JWT_REFRESH_TTL":				&c.JWT.RefreshTTL,
+		"JWT_MFA_TTL":					&c.JWT.MFATTL,
+		"PASSWORD_RESET_TTL":			&c.PasswordResetTTL,
+		"EMAIL_VERIFICATION_TTL":		&c.EmailVerificationTTL,
+		"REQUIRE_VERIFIED_EMAIL":		&c.RequireVerifiedEmail,
+		"MFA_ISSUER":					&c.MFAIssuer,
+		"SHUTDOWN_TIMEOUT":				&c.ShutdownTimeout,
+		"MAIL_DRIVER":					&c.Mail.Driver,
+		"MAIL_DIR":						&c.Mail.Dir,
+		"MAIL_FROM":					&c.Mail.From,
+		"LOCKOUT_MAX_ATTEMPTS":			&c.Lockout.MaxAttempts,
+		"LOCKOUT_IP_MAX_ATTEMPTS":		&c.Lockout.IPMaxAttempts,
+		"LOCKOUT_BASE_DELAY":			&c.Lockout.BaseDelay,
+		"LOCKOUT_MAX_DELAY":			&c.Lockout.MaxDelay,
+		"LOCKOUT_WINDOW":				&c.Lockout.Window,
+		"RATE_LIMIT_ENABLED":			&c.RateLimit.Enabled,
+		"RATE_LIMIT_STORE":				&c.RateLimit.Store,
+		"RATE_LIMIT_IP_ALGORITHM":		&c.RateLimit.IP.Algorithm,
+		"RATE_LIMIT_IP_LIMIT":			&c.RateLimit.IP.Limit,
+		"RATE_LIMIT_IP_WINDOW":			&c.RateLimit.IP.Window,
+		"RATE_LIMIT_AUTH_ALGORITHM":	&c.RateLimit.Auth.Algorithm,
+		"RATE_LIMIT_AUTH_LIMIT":		&c.RateLimit.Auth.Limit,
+		"RATE_LIMIT_AUTH_WINDOW":		&c.RateLimit.Auth.Window,
+		"RATE_LIMIT_AUTH_KEY":			&c.RateLimit.Auth.Key,
+		"RATE_LIMIT_API_ALGORITHM":		&c.RateLimit.API.Algorithm,
+		"RATE_LIMIT_API_LIMIT":			&c.RateLimit.API.Limit,
+		"RATE_LIMIT_API_WINDOW":		&c.RateLimit.API.Window,
+		"RATE_LIMIT_API_KEY":			&c.RateLimit.API.Key,
+		"LOG_LEVEL":					&c.Log.Level,
+		"LOG_FORMAT":					&c.Log.Format,
+		"METRICS_ENABLED":				&c.Metrics.Enabled,
+		"METRICS_TOKEN":				&c.Metrics.Token,
+		"TRACING_EXPORTER":				&c.Tracing.Exporter,
+		"TRACING_ENDPOINT":				&c.Tracing.Endpoint,
+		"TRACING_SERVICE_NAME":			&c.Tracing.ServiceName,
+	}
+}
+
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	for name, target := range c.envVars() {
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			continue
+		}
+
+		if err := setValue(target, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func setValue(target any, value string) error {
+	switch field := target.(type) {
+	case *string:
+		*field = value
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field = parsed
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		*field = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", value)
+		}
+		*field = parsed
+	case *[]string:
+		// A comma separated list, e.g. "10.0.0.0/8, 127.0.0.1".
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		*field = values
+	default:
+		return fmt.Errorf("unsupported config type %T", target)
+	}
+
+	return nil
+}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Validate reports every invalid database setting at once.
+func (c DatabaseConfig) Validate() error {
+	var errs []error
+
+	switch c.Driver {
+	case DriverPostgres:
+		required := []struct{ name, value string }{
+			{"DB_HOST", c.Host},
+			{"DB_USER", c.User},
+			{"DB_NAME", c.Name},
+		}
+		for _, field := range required {
+			if field.value == "" {
+				errs = append(errs, fmt.Errorf("%s is required", field.name))
+			}
+		}
+
+		if !slices.Contains(sslModes, c.SSLMode) {
+			errs = append(errs, fmt.Errorf("DB_SSLMODE must be one of %s", strings.Join(sslModes, ", ")))
+		}
+		if c.ConnectTimeout <= 0 {
+			errs = append(errs, errors.New("DB_CONNECT_TIMEOUT must be positive"))
+		}
+		if c.ConnectAttempts < 1 {
+			errs = append(errs, errors.New("DB_CONNECT_ATTEMPTS must be at least 1"))
+		}
+		if c.ConnectBackoff < 0 {
+			errs = append(errs, errors.New("DB_CONNECT_BACKOFF must not be negative"))
+		}
+	case DriverSQLite:
+		if c.Path == "" {
+			errs = append(errs, errors.New("DB_PATH is required for the sqlite driver"))
+		}
+	case DriverMemory:
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER must be one of %s, %s, %s", DriverPostgres, DriverSQLite, DriverMemory))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.PublicURL == "" {
+		errs = append(errs, errors.New("PUBLIC_URL is required"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+
+	if err := c.Database.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch c.JWT.Algorithm {
+	case AlgorithmHS256:
+		if len(c.JWT.Secret) < MinSecretLength {
+			errs = append(errs, fmt.Errorf("JWT_SECRET must be at least %d characters", MinSecretLength))
+		}
+	case AlgorithmRS256, AlgorithmEdDSA:
+		if c.JWT.KeysDir == "" && c.JWT.SigningKey == "" {
+			errs = append(errs, fmt.Errorf("JWT_KEYS_DIR or JWT_SIGNING_KEY is required for %s", c.JWT.Algorithm))
+		}
+		if c.JWT.SigningKey != "" && c.JWT.ActiveKeyID == "" {
+			errs = append(errs, errors.New("JWT_ACTIVE_KEY_ID is required with JWT_SIGNING_KEY"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("JWT_ALGORITHM must be one of %s, %s, %s", AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA))
+	}
+	if len(c.JWT.RefreshSecret) < MinSecretLength {
+		errs = append(errs, fmt.Errorf("JWT_REFRESH_SECRET must be at least %d characters", MinSecretLength))
+	}
+	if c.JWT.Secret != "" && c.JWT.Secret == c.JWT.RefreshSecret {
+		errs = append(errs, errors.New("JWT_SECRET and JWT_REFRESH_SECRET must differ"))
+	}
+
+	if c.JWT.Issuer == "" {
+		errs = append(errs, errors.New("JWT_ISSUER is required"))
+	}
+	if c.JWT.Audience == "" {
+		errs = append(errs, errors.New("JWT_AUDIENCE is required"))
+	}
+	if c.JWT.Leeway < 0 || c.JWT.Leeway >= c.JWT.AccessTTL {
+		errs = append(errs, errors.New("JWT_LEEWAY must not be negative and must be shorter than JWT_ACCESS_TTL"))
+	}
+
+	if c.JWT.AccessTTL <= 0 {
+		errs = append(errs, errors.New("JWT_ACCESS_TTL must be positive"))
+	}
+	if c.JWT.RefreshTTL <= c.JWT.AccessTTL {
+		errs = append(errs, errors.New("JWT_REFRESH_TTL must be longer than JWT_ACCESS_TTL"))
+	}
+	if c.JWT.MFATTL <= 0 {
+		errs = append(errs, errors.New("JWT_MFA_TTL must be positive"))
+	}
+
+	if c.PasswordResetTTL <= 0 {
+		errs = append(errs, errors.New("PASSWORD_RESET_TTL must be positive"))
+	}
+	if c.MFAIssuer == "" {
+		errs = append(errs, errors.New("MFA_ISSUER is required"))
+	}
+	if c.EmailVerificationTTL <= 0 {
+		errs = append(errs, errors.New("EMAIL_VERIFICATION_TTL must be positive"))
+	}
+
+	if c.ProxyHeader != "" && len(c.TrustedProxies) == 0 {
+		errs = append(errs, errors.New("PROXY_HEADER needs TRUSTED_PROXIES, otherwise any client can send its own address"))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP address or CIDR range", proxy))
+			}
+		}
+	}
+
+	if c.Lockout.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("LOCKOUT_MAX_ATTEMPTS must be positive"))
+	}
+	if c.Lockout.IPMaxAttempts < 0 {
+		errs = append(errs, errors.New("LOCKOUT_IP_MAX_ATTEMPTS must not be negative"))
+	}
+	if c.Lockout.IPMaxAttempts > 0 && c.ProxyHeader == "" {
+		errs = append(errs, errors.New("LOCKOUT_IP_MAX_ATTEMPTS needs PROXY_HEADER, otherwise all clients share the proxy address"))
+	}
+	if c.Lockout.BaseDelay <= 0 || c.Lockout.MaxDelay < c.Lockout.BaseDelay {
+		errs = append(errs, errors.New("LOCKOUT_BASE_DELAY must be positive and not longer than LOCKOUT_MAX_DELAY"))
+	}
+	if c.Lockout.Window <= 0 {
+		errs = append(errs, errors.New("LOCKOUT_WINDOW must be positive"))
+	}
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Store {
+		case RateLimitMemory:
+		case RateLimitDatabase:
+			if c.Database.Driver == DriverMemory {
+				errs = append(errs, errors.New("RATE_LIMIT_STORE database needs a database driver"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_STORE must be one of %s, %s", RateLimitMemory, RateLimitDatabase))
+		}
+
+		errs = append(errs, c.RateLimit.IP.validate("RATE_LIMIT_IP")...)
+		if c.RateLimit.IP.Key != RateLimitKeyIP {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_IP_KEY must be %s", RateLimitKeyIP))
+		}
+		errs = append(errs, c.RateLimit.Auth.validate("RATE_LIMIT_AUTH")...)
+		errs = append(errs, c.RateLimit.API.validate("RATE_LIMIT_API")...)
+	}
+
+	switch c.Mail.Driver {
+	case "":
+		errs = append(errs, errors.New("MAIL_DRIVER is required"))
+	case MailLog:
+	case MailFile:
+		if c.Mail.Dir == "" {
+			errs = append(errs, errors.New("MAIL_DIR is required for the file mail driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_DRIVER must be one of %s, %s", MailLog, MailFile))
+	}
+
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+
+	if c.Password.Algorithm != PasswordArgon2id && c.Password.Algorithm != PasswordBcrypt {
+		errs = append(errs, fmt.Errorf("PASSWORD_ALGORITHM must be one of %s, %s", PasswordArgon2id, PasswordBcrypt))
+	}
+	if c.Password.Argon2Parallelism < 1 || c.Password.Argon2Parallelism > 255 {
+		errs = append(errs, errors.New("PASSWORD_ARGON2_PARALLELISM must be between 1 and 255"))
+	}
+	// Argon2 needs at least 8 KiB per lane.
+	if c.Password.Argon2Memory < 8*c.Password.Argon2Parallelism {
+		errs = append(errs, errors.New("PASSWORD_ARGON2_MEMORY must be at least 8 KiB per lane"))
+	}
+	if c.Password.Argon2Iterations < 1 {
+		errs = append(errs, errors.New("PASSWORD_ARGON2_ITERATIONS must be positive"))
+	}
+	// The request validation already asks for 8 characters.
+	if c.Password.MinLength < 8 {
+		errs = append(errs, errors.New("PASSWORD_MIN_LENGTH must be at least 8"))
+	}
+	if c.Password.MinEntropy < 0 {
+		errs = append(errs, errors.New("PASSWORD_MIN_ENTROPY must not be negative"))
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, errors.New("LOG_LEVEL must be one of debug, info, warn, error"))
+	}
+	if c.Log.Format != LogJSON && c.Log.Format != LogText {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be one of %s, %s", LogJSON, LogText))
+	}
+
+	if c.Metrics.Enabled && len(c.Metrics.Token) < MinSecretLength {
+		errs = append(errs, fmt.Errorf("METRICS_TOKEN must be at least %d characters", MinSecretLength))
+	}
+
+	if c.Tracing.Exporter != TracingNone && c.Tracing.Exporter != TracingStdout && c.Tracing.Exporter != TracingOTLP {
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER must be one of %s, %s, %s", TracingNone, TracingStdout, TracingOTLP))
+	}
+	if c.Tracing.ServiceName == "" {
+		errs = append(errs, errors.New("TRACING_SERVICE_NAME is required"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateServerless reports the settings that only work on a long running
+// server. Each serverless instance has its own memory, so memory rate limits
+// count per instance, and every client reaches it through the platform
+// proxy, so without PROXY_HEADER all clients share one address.
+func (c *Config) ValidateServerless() error {
+	var errs []error
+
+	if c.RateLimit.Enabled && c.RateLimit.Store != RateLimitDatabase {
+		errs = append(errs, errors.New("RATE_LIMIT_STORE must be database on serverless deployments"))
+	}
+	if c.RateLimit.Enabled && c.ProxyHeader == "" {
+		errs = append(errs, errors.New("PROXY_HEADER is required on serverless deployments"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks one policy, prefix is the start of its variable names.
+func (p RateLimitPolicy) validate(prefix string) []error {
+	var errs []error
+
+	if p.Algorithm != RateLimitTokenBucket && p.Algorithm != RateLimitSlidingWindow {
+		errs = append(errs, fmt.Errorf("%s_ALGORITHM must be one of %s, %s", prefix, RateLimitTokenBucket, RateLimitSlidingWindow))
+	}
+	if p.Limit <= 0 {
+		errs = append(errs, fmt.Errorf("%s_LIMIT must be positive", prefix))
+	}
+	if p.Window <= 0 {
+		errs = append(errs, fmt.Errorf("%s_WINDOW must be positive", prefix))
+	}
+	if p.Key != RateLimitKeyIP && p.Key != RateLimitKeyUser && p.Key != RateLimitKeyAPIKey {
+		errs = append(errs, fmt.Errorf("%s_KEY must be one of %s, %s, %s", prefix, RateLimitKeyIP, RateLimitKeyUser, RateLimitKeyAPIKey))
+	}
+
+	return errs
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig() *Config {
+	cfg := Default()
+	cfg.ClientURL = "http://localhost:5173"
+	cfg.Database.Host = "localhost"
+	cfg.Database.User = "postgres"
+	cfg.Database.Name = "app"
+	cfg.JWT.Secret = "access-secret-that-is-long-enough!"
+	cfg.JWT.RefreshSecret = "refresh-secret-that-is-long-enough"
+	cfg.Mail.Driver = MailLog
+	return cfg
+}
+
+func TestValidate_Success(t *testing.T) {
+	assert.NoError(t, validConfig().Validate())
+}
+
+func TestValidate_Error(t *testing.T) {
+	cfg := validConfig()
+	cfg.Database.Host = ""
+	cfg.JWT.Secret = ""
+	cfg.BcryptCost = 2
+	cfg.Database.ConnectAttempts = 0
+	cfg.ShutdownTimeout = 0
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "DB_HOST is required")
+	assert.ErrorContains(t, err, "JWT_SECRET must be at least 32 characters")
+	assert.ErrorContains(t, err, "BCRYPT_COST must be between")
+	assert.ErrorContains(t, err, "DB_CONNECT_ATTEMPTS must be at least 1")
+	assert.ErrorContains(t, err, "SHUTDOWN_TIMEOUT must be positive")
+}
+
+func TestDatabaseConfig_Validate(t *testing.T) {
+	cfg := Default()
+	assert.ErrorContains(t, cfg.Database.Validate(), "DB_HOST is required")
+
+	// The other settings are not needed to reach the database.
+	cfg.Database.Host = "localhost"
+	cfg.Database.User = "postgres"
+	cfg.Database.Name = "app"
+	assert.NoError(t, cfg.Database.Validate())
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_WithoutClientURL(t *testing.T) {
+	cfg := validConfig()
+	cfg.ClientURL = ""
+
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Leeway(t *testing.T) {
+	cfg := validConfig()
+	cfg.JWT.Leeway = 0
+	assert.NoError(t, cfg.Validate())
+
+	cfg.JWT.Leeway = -time.Second
+	assert.ErrorContains(t, cfg.Validate(), "JWT_LEEWAY must not be negative")
+}
+
+func TestValidate_LockoutIPNeedsProxyHeader(t *testing.T) {
+	cfg := validConfig()
+	cfg.Lockout.IPMaxAttempts = 20
+
+	assert.ErrorContains(t, cfg.Validate(), "LOCKOUT_IP_MAX_ATTEMPTS needs PROXY_HEADER")
+
+	cfg.ProxyHeader = "X-Forwarded-For"
+	cfg.TrustedProxies = []string{"10.0.0.0/8"}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_ProxyHeaderNeedsTrustedProxies(t *testing.T) {
+	cfg := validConfig()
+	cfg.ProxyHeader = "X-Forwarded-For"
+	assert.ErrorContains(t, cfg.Validate(), "PROXY_HEADER needs TRUSTED_PROXIES")
+
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"}
+	assert.ErrorContains(t, cfg.Validate(), `"proxy.local" is not an IP address or CIDR range`)
+
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "127.0.0.1", "::/0"}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateServerless(t *testing.T) {
+	cfg := validConfig()
+
+	err := cfg.ValidateServerless()
+	assert.ErrorContains(t, err, "RATE_LIMIT_STORE must be database")
+	assert.ErrorContains(t, err, "PROXY_HEADER is required")
+
+	cfg.RateLimit.Store = RateLimitDatabase
+	cfg.ProxyHeader = "X-Forwarded-For"
+	cfg.TrustedProxies = []string{"0.0.0.0/0", "::/0"}
+	assert.NoError(t, cfg.Validate())
+	assert.NoError(t, cfg.ValidateServerless())
+}
+
+func TestValidate_MailDriverRequired(t *testing.T) {
+	cfg := validConfig()
+	cfg.Mail.Driver = ""
+
+	assert.ErrorContains(t, cfg.Validate(), "MAIL_DRIVER is required")
+}
+
+func TestValidate_RateLimit(t *testing.T) {
+	cfg := validConfig()
+	cfg.RateLimit.Auth.Algorithm = "leaky_bucket"
+	cfg.RateLimit.API.Key = "session"
+	cfg.RateLimit.Store = RateLimitDatabase
+	cfg.Database.Driver = DriverMemory
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "RATE_LIMIT_AUTH_ALGORITHM must be one of")
+	assert.ErrorContains(t, err, "RATE_LIMIT_API_KEY must be one of")
+	assert.ErrorContains(t, err, "RATE_LIMIT_STORE database needs a database driver")
+
+	cfg.RateLimit.Enabled = false
+	cfg.RateLimit.Store = RateLimitMemory
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Metrics(t *testing.T) {
+	cfg := validConfig()
+	cfg.Metrics.Enabled = true
+
+	assert.ErrorContains(t, cfg.Validate(), "METRICS_TOKEN must be at least 32 characters")
+
+	cfg.Metrics.Token = "metrics-token-that-is-long-enough"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Tracing(t *testing.T) {
+	cfg := validConfig()
+	cfg.Tracing.Exporter = "jaeger"
+	cfg.Tracing.ServiceName = ""
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "TRACING_EXPORTER must be one of none, stdout, otlp")
+	assert.ErrorContains(t, err, "TRACING_SERVICE_NAME is required")
+}
+
+func TestLoadEnv(t *testing.T) {
+	cfg := Default()
+	env := map[string]string{
+		"DB_HOST":			"db.internal",
+		"DB_PORT":			"6543",
+		"JWT_ACCESS_TTL":	"5m",
+		"TRUSTED_PROXIES":	"10.0.0.0/8, 127.0.0.1,",
+	}
+
+	err := cfg.loadEnv(func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, 6543, cfg.Database.Port)
+	assert.Equal(t, 5*time.Minute, cfg.JWT.AccessTTL)
+	assert.Equal(t, []string{"10.0.0.0/8", "127.0.0.1"}, cfg.TrustedProxies)
+	assert.Equal(t, "require", cfg.Database.SSLMode)
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(yamlPath, []byte("port: \"3000\"\ndatabase:\n  host: yaml-host\njwt:\n  access_ttl: 10m\n"), 0o600)
+
+	tomlPath := filepath.Join(dir, "config.toml")
+	os.WriteFile(tomlPath, []byte("port = \"4000\"\n[database]\nhost = \"toml-host\"\n[jwt]\naccess_ttl = \"15m\"\n"), 0o600)
+
+	cfg := Default()
+	assert.NoError(t, cfg.loadFile(yamlPath))
+	assert.Equal(t, "3000", cfg.Port)
+	assert.Equal(t, "yaml-host", cfg.Database.Host)
+	assert.Equal(t, 10*time.Minute, cfg.JWT.AccessTTL)
+
+	cfg = Default()
+	assert.NoError(t, cfg.loadFile(tomlPath))
+	assert.Equal(t, "4000", cfg.Port)
+	assert.Equal(t, "toml-host", cfg.Database.Host)
+	assert.Equal(t, 15*time.Minute, cfg.JWT.AccessTTL)
+}
@@ -0,0 +1,331 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Models lists every GORM model whose table is created by the migrations.
+// Drift compares them with the applied schema.
+var Models = []interface{}{
+	&models.User{},
+	&models.Product{},
+	&models.RefreshToken{},
+	&models.Role{},
+	&models.Permission{},
+	&models.PasswordResetToken{},
+	&models.EmailVerificationToken{},
+	&models.RecoveryCode{},
+	&models.APIKey{},
+	&models.LoginAttempt{},
+	&models.RateLimit{},
+	&models.UsedMFAToken{},
+}
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version	int64
+	Name	string
+	Up		string
+	Down	string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt	*time.Time
+}
+
+// schemaMigration is a row of the schema_migrations table.
+type schemaMigration struct {
+	Version		int64		`gorm:"primaryKey;autoIncrement:false"`
+	Name		string
+	AppliedAt	time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// LoadMigrations reads the embedded migration files ordered by version.
+func LoadMigrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+type Migrator struct {
+	DB			*gorm.DB
+	Migrations	[]Migration
+}
+
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	return m.DB.WithContext(ctx).AutoMigrate(&schemaMigration{})
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]schemaMigration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.appliedRows(ctx)
+}
+
+func (m *Migrator) appliedRows(ctx context.Context) (map[int64]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := m.DB.WithContext(ctx).Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version: migration.Version,
+				Name: migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.Migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Status reports every known migration with the time it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Pending returns the migrations that are not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Current fails when a migration is not applied yet. Unlike Pending it only
+// reads, a missing schema_migrations table means nothing is applied.
+func (m *Migrator) Current(ctx context.Context) error {
+	applied := map[int64]schemaMigration{}
+	if m.DB.WithContext(ctx).Migrator().HasTable(&schemaMigration{}) {
+		var err error
+		if applied, err = m.appliedRows(ctx); err != nil {
+			return err
+		}
+	}
+
+	var pending []string
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, fmt.Sprintf("%04d_%s", migration.Version, migration.Name))
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migrations: %s", len(pending), strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+// Drift lists the differences between the GORM models and the tables in the
+// database: missing tables, missing columns and columns no model knows about.
+func (m *Migrator) Drift(ctx context.Context) ([]string, error) {
+	db := m.DB.WithContext(ctx)
+	migrator := db.Migrator()
+
+	var drift []string
+	for _, model := range Models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		table := stmt.Schema.Table
+
+		if !migrator.HasTable(table) {
+			drift = append(drift, fmt.Sprintf("table %s is missing", table))
+			continue
+		}
+
+		columnTypes, err := migrator.ColumnTypes(model)
+		if err != nil {
+			return nil, err
+		}
+
+		existing := map[string]bool{}
+		for _, column := range columnTypes {
+			existing[column.Name()] = true
+		}
+
+		known := map[string]bool{}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			known[field.DBName] = true
+			if !existing[field.DBName] {
+				drift = append(drift, fmt.Sprintf("column %s.%s is missing", table, field.DBName))
+			}
+		}
+
+		for _, column := range columnTypes {
+			if !known[column.Name()] {
+				drift = append(drift, fmt.Sprintf("column %s.%s is not in the model", table, column.Name()))
+			}
+		}
+	}
+
+	return drift, nil
+}
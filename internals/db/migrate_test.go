@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i, migration := range migrations {
+		if i > 0 && migration.Version <= migrations[i-1].Version {
+			t.Errorf("migration %d is not ordered after %d", migration.Version, migrations[i-1].Version)
+		}
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+}
+
+func TestLoadMigrations_MissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_init.up.sql":		{Data: []byte("CREATE TABLE a (id int);")},
+		"migrations/0001_init.down.sql":	{Data: []byte("DROP TABLE a;")},
+		"migrations/0002_more.up.sql":		{Data: []byte("CREATE TABLE b (id int);")},
+	}
+
+	_, err := loadMigrations(fsys, "migrations")
+
+	assert.Error(t, err)
+}
+
+func TestMigrator_Current(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, _ := conn.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	migrator := &Migrator{DB: conn, Migrations: []Migration{
+		{Version: 1, Name: "init", Up: "CREATE TABLE a (id int);", Down: "DROP TABLE a;"},
+		{Version: 2, Name: "more", Up: "CREATE TABLE b (id int);", Down: "DROP TABLE b;"},
+	}}
+	ctx := context.Background()
+
+	assert.EqualError(t, migrator.Current(ctx), "2 pending migrations: 0001_init, 0002_more")
+	assert.False(t, conn.Migrator().HasTable(&schemaMigration{}), "Current must not create the table")
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, migrator.Current(ctx))
+
+	_, err = migrator.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.EqualError(t, migrator.Current(ctx), "1 pending migrations: 0002_more")
+}
@@ -0,0 +1,26 @@
+-- Baseline schema. IF NOT EXISTS lets databases that were created by the old
+-- AutoMigrate call adopt the migration history without changes.
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS users (
+    id          uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name        text,
+    email       text,
+    password    text,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS products (
+    id          uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name        text,
+    price       numeric,
+    user_id     uuid REFERENCES users (id),
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_products_user_id ON products (user_id);
+CREATE INDEX IF NOT EXISTS idx_products_created_at ON products (created_at, id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id              uuid PRIMARY KEY,
+    user_id         uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id       uuid NOT NULL,
+    replaced_by_id  uuid,
+    expires_at      timestamptz NOT NULL,
+    revoked_at      timestamptz,
+    created_at      timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id      uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name    text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id      uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name    text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id         uuid NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission_id   uuid NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id uuid NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+-- Built in roles, keep in sync with models.RolePermissions.
+INSERT INTO roles (name) VALUES ('admin'), ('seller'), ('customer')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name) VALUES
+    ('products:read'), ('products:write'), ('roles:read'), ('roles:assign')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON
+    r.name = 'admin'
+    OR (r.name = 'seller' AND p.name IN ('products:read', 'products:write'))
+    OR (r.name = 'customer' AND p.name = 'products:read')
+ON CONFLICT DO NOTHING;
+
+-- Users registered before roles existed keep their ability to sell.
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id
+FROM users u
+JOIN roles r ON r.name = 'seller'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+
+-- Accounts created before verification existed are trusted as they are.
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id          uuid PRIMARY KEY,
+    user_id     uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash  text NOT NULL,
+    expires_at  timestamptz NOT NULL,
+    used_at     timestamptz,
+    created_at  timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_verification_tokens_token_hash ON email_verification_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens (user_id);
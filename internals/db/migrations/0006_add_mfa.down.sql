@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_enabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_secret;
@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_secret text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_enabled_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id          uuid PRIMARY KEY,
+    user_id     uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash   text NOT NULL,
+    used_at     timestamptz,
+    created_at  timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
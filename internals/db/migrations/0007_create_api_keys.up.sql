@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id              uuid PRIMARY KEY,
+    user_id         uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name            text NOT NULL,
+    prefix          text NOT NULL,
+    key_hash        text NOT NULL,
+    scopes          text NOT NULL DEFAULT '[]',
+    expires_at      timestamptz,
+    last_used_at    timestamptz,
+    revoked_at      timestamptz,
+    created_at      timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'users:unlock';
+
+DROP TABLE IF EXISTS login_attempts;
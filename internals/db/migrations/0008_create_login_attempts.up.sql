@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key             text PRIMARY KEY,
+    failures        integer NOT NULL DEFAULT 0,
+    locked_until    timestamptz,
+    updated_at      timestamptz NOT NULL
+);
+
+-- Keep in sync with models.RolePermissions.
+INSERT INTO permissions (name) VALUES ('users:unlock')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'users:unlock'
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
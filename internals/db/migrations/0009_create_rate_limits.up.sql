@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key             text PRIMARY KEY,
+    value           double precision NOT NULL DEFAULT 0,
+    previous        double precision NOT NULL DEFAULT 0,
+    at              timestamptz NOT NULL,
+    expires_at      timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_expires_at ON rate_limits (expires_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS used_mfa_tokens;
+
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_last_counter;
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_last_counter bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS used_mfa_tokens (
+    id              uuid PRIMARY KEY,
+    expires_at      timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_used_mfa_tokens_expires_at ON used_mfa_tokens (expires_at);
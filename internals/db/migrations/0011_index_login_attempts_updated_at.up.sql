@@ -0,0 +1,2 @@
+-- Fail deletes the keys that are past their window, by updated_at.
+CREATE INDEX IF NOT EXISTS idx_login_attempts_updated_at ON login_attempts (updated_at);
@@ -1,29 +1,80 @@
 package db
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"log/slog"
+	"time"
 
-	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func ConnectDB()*gorm.DB {
-	host 		:= os.Getenv("DB_HOST")
-	user 		:= os.Getenv("DB_USER")
-	password	:= os.Getenv("DB_PASSWORD")
-	dbname		:= os.Getenv("DB_NAME")
-
-	dsn := fmt.Sprintf("host=%v user=%v password=%v dbname=%v  sslmode=require", host, user, password, dbname)
-    db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-    if err != nil {
-        panic("failed to connect database")
-    }else {
-		fmt.Println("Success connect to database!")
+// ConnectDB opens the database. The schema is not touched here, run
+// `go run ./cmd/migrate up` to apply the migrations.
+//
+// Postgres may still be starting, e.g. next to the app in docker compose,
+// so a failed connection is retried with backoff as cfg.Connect* says. ctx
+// stops the retries.
+func ConnectDB(ctx context.Context, cfg config.DatabaseConfig, logger *slog.Logger) (*gorm.DB, error) {
+	backoff := cfg.ConnectBackoff
+
+	for attempt := 1; ; attempt++ {
+		db, err := openPostgres(ctx, cfg, logger)
+		if err == nil {
+			logger.Info("connected to database", "host", cfg.Host, "name", cfg.Name, "attempt", attempt)
+			return db, nil
+		}
+
+		if attempt >= cfg.ConnectAttempts {
+			return nil, fmt.Errorf("connect to database after %d attempts: %w", attempt, err)
+		}
+
+		logger.Warn("connect to database failed, retrying", "attempt", attempt, "retry_in", backoff.String(), "error", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connect to database: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// openPostgres makes one attempt, bounded by cfg.ConnectTimeout.
+func openPostgres(ctx context.Context, cfg config.DatabaseConfig, logger *slog.Logger) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{
+		Logger: logging.NewGormLogger(logger),
+		// Pinged below, with a timeout.
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	db.Debug().AutoMigrate(&models.Product{}, &models.User{})
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+
+	if err := sqlDB.PingContext(pingCtx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
 
-	return db
-}
\ No newline at end of file
+// Close closes the connection pool of db.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
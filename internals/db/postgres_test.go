@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// unreachable points at a port nothing listens on.
+func unreachable() config.DatabaseConfig {
+	cfg := config.Default().Database
+	cfg.Host = "127.0.0.1"
+	cfg.Port = 1
+	cfg.User = "postgres"
+	cfg.Name = "app"
+	cfg.SSLMode = "disable"
+	cfg.ConnectTimeout = time.Second
+	cfg.ConnectBackoff = 10 * time.Millisecond
+	return cfg
+}
+
+func TestConnectDB_RetriesThenFails(t *testing.T) {
+	cfg := unreachable()
+	cfg.ConnectAttempts = 3
+
+	start := time.Now()
+	db, err := ConnectDB(context.Background(), cfg, logging.Discard())
+
+	assert.Nil(t, db)
+	assert.ErrorContains(t, err, "after 3 attempts")
+	// Waited 10ms and then 20ms between the attempts.
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+}
+
+func TestConnectDB_StopsWithContext(t *testing.T) {
+	cfg := unreachable()
+	cfg.ConnectAttempts = 100
+	cfg.ConnectBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := ConnectDB(ctx, cfg, logging.Discard())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
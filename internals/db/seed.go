@@ -0,0 +1,71 @@
+package db
+
+import (
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SeedRoles makes sure the built in roles and their permissions exist. On
+// Postgres the migrations do this, Bootstrap uses it for SQLite.
+func SeedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for roleName, permissionNames := range models.RolePermissions {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Role{Name: roleName}).Error; err != nil {
+				return err
+			}
+
+			var role models.Role
+			if err := tx.First(&role, "name = ?", roleName).Error; err != nil {
+				return err
+			}
+
+			var permissions []models.Permission
+			for _, name := range permissionNames {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Permission{Name: name}).Error; err != nil {
+					return err
+				}
+
+				var permission models.Permission
+				if err := tx.First(&permission, "name = ?", name).Error; err != nil {
+					return err
+				}
+				permissions = append(permissions, permission)
+			}
+
+			if err := tx.Model(&role).Association("Permissions").Replace(permissions); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// BackfillRoles gives roleName to every user that has no role yet.
+func BackfillRoles(db *gorm.DB, roleName string) error {
+	var role models.Role
+	if err := db.First(&role, "name = ?", roleName).Error; err != nil {
+		return err
+	}
+
+	return db.Exec(`INSERT INTO user_roles (user_id, role_id)
+		SELECT id, ? FROM users
+		WHERE NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id)`, role.ID).Error
+}
+
+// PromoteAdmin gives the admin role to the user registered with email, so the
+// first administrator can be bootstrapped without touching the database.
+func PromoteAdmin(db *gorm.DB, email string) error {
+	var user models.User
+	if err := db.First(&user, "email = ?", email).Error; err != nil {
+		return err
+	}
+
+	var role models.Role
+	if err := db.First(&role, "name = ?", models.RoleAdmin).Error; err != nil {
+		return err
+	}
+
+	return db.Model(&user).Association("Roles").Append(&role)
+}
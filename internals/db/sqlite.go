@@ -0,0 +1,55 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/glebarez/sqlite"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+// ConnectSQLite opens the SQLite database at cfg.Path and creates the schema
+// from the GORM models. The SQL migrations target Postgres, so SQLite is
+// only meant for tests, offline demos and local development.
+func ConnectSQLite(cfg config.DatabaseConfig, logger *slog.Logger) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(cfg.Path+"?_pragma=foreign_keys(1)"), &gorm.Config{Logger: logging.NewGormLogger(logger)})
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+
+	if cfg.Path == ":memory:" {
+		// Every connection would get its own empty in-memory database.
+		sqlDB, _ := db.DB()
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	if err := Bootstrap(db); err != nil {
+		Close(db)
+		return nil, fmt.Errorf("bootstrap sqlite database: %w", err)
+	}
+
+	return db, nil
+}
+
+// Bootstrap creates the tables of every model and seeds the built in roles.
+// Like migration 0003, the first bootstrap that creates the roles gives the
+// seller role to users registered before roles existed.
+func Bootstrap(db *gorm.DB) error {
+	backfill := !db.Migrator().HasTable(&models.Role{})
+
+	if err := db.AutoMigrate(Models...); err != nil {
+		return err
+	}
+
+	if err := SeedRoles(db); err != nil {
+		return err
+	}
+
+	if !backfill {
+		return nil
+	}
+	return BackfillRoles(db, models.RoleSeller)
+}
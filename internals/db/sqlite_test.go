@@ -0,0 +1,45 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectSQLite_BackfillsRolesOnce(t *testing.T) {
+	cfg := config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "app.db")}
+
+	// A database created before roles existed.
+	conn, err := ConnectSQLite(cfg, logging.Discard())
+	require.NoError(t, err)
+	require.NoError(t, conn.Exec("DELETE FROM role_permissions").Error)
+	require.NoError(t, conn.Migrator().DropTable("user_roles", &models.Role{}))
+	legacy := models.User{ID: uuid.New(), Name: "Taufik", Email: "taufik@dev.com", Password: "hash"}
+	require.NoError(t, conn.Omit("Roles").Create(&legacy).Error)
+	require.NoError(t, Close(conn))
+
+	conn, err = ConnectSQLite(cfg, logging.Discard())
+	require.NoError(t, err)
+
+	var user models.User
+	require.NoError(t, conn.Preload("Roles").First(&user, "id = ?", legacy.ID).Error)
+	assert.Equal(t, []string{models.RoleSeller}, user.RoleNames())
+
+	// Roles removed later are not granted again on the next start.
+	require.NoError(t, conn.Model(&user).Association("Roles").Clear())
+	require.NoError(t, Close(conn))
+
+	conn, err = ConnectSQLite(cfg, logging.Discard())
+	require.NoError(t, err)
+	defer Close(conn)
+
+	user = models.User{}
+	require.NoError(t, conn.Preload("Roles").First(&user, "id = ?", legacy.ID).Error)
+	assert.Empty(t, user.RoleNames())
+}
@@ -5,19 +5,23 @@ import (
 )
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
+// LoginResponse carries either the access token or, for users with two
+// factor authentication, the token to send to /auth/mfa/verify.
 type LoginResponse struct {
-	AccessToken string `json:"accessToken"`
+	AccessToken string `json:"accessToken,omitempty"`
+	MFARequired bool   `json:"mfaRequired,omitempty"`
+	MFAToken    string `json:"mfaToken,omitempty"`
 }
 
 type RegisterRequest struct {
-	Name         string `json:"name"`
-	Email        string `json:"email"`
-	Password     string `json:"password"`
-	ConfPassword string `json:"confPassword"`
+	Name         string `json:"name" validate:"required,min=2,max=100"`
+	Email        string `json:"email" validate:"required,email,max=254"`
+	Password     string `json:"password" validate:"required,min=8,max=1024"`
+	ConfPassword string `json:"confPassword" validate:"required,eqfield=Password"`
 }
 
 type RegisterResponse struct {
@@ -25,4 +29,69 @@ type RegisterResponse struct {
 	Email        string 	`json:"email"`
 	CreatedAt	 time.Time	`json:"createdAt"`
 	UpdatedAt	 time.Time	`json:"updatedAt"`
-}
\ No newline at end of file
+}
+type AssignRolesRequest struct {
+	Roles []string `json:"roles" validate:"required,min=1,dive,required"`
+}
+
+type UserRolesResponse struct {
+	ID			string		`json:"id"`
+	Roles		[]string	`json:"roles"`
+	Permissions	[]string	`json:"permissions"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+type ResetPasswordRequest struct {
+	Token        string `json:"token" validate:"required"`
+	Password     string `json:"password" validate:"required,min=8,max=1024"`
+	ConfPassword string `json:"confPassword" validate:"required,eqfield=Password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	Password        string `json:"password" validate:"required,min=8,max=1024"`
+	ConfPassword    string `json:"confPassword" validate:"required,eqfield=Password"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfaToken" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type MFAEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// CreatedAPIKeyResponse is the only response that contains the key itself.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
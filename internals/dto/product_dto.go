@@ -7,14 +7,42 @@ import (
 )
 
 type ProductRequest struct {
-	Name   string  		`json:"name"`
-	Price  float64 		`json:"price"`
+	Name   string  		`json:"name" validate:"required,min=1,max=255"`
+	Price  float64 		`json:"price" validate:"gt=0"`
 	UserID uuid.UUID	`json:"userId"`
 }
+
+// ProductPatchRequest only changes the fields sent by the client.
+type ProductPatchRequest struct {
+	Name   *string  	`json:"name" validate:"omitempty,min=1,max=255"`
+	Price  *float64 	`json:"price" validate:"omitempty,gt=0"`
+}
+
 type ProductResponse struct {
 	ID			uuid.UUID		`json:"id"`
 	Name   		string  		`json:"name"`
 	Price  		float64 		`json:"price"`
+	UserID		uuid.UUID		`json:"userId"`
 	CreatedAt	time.Time		`json:"createdAt"`
+	UpdatedAt	time.Time		`json:"updatedAt"`
 }
 
+type ProductQuery struct {
+	Page		int
+	Limit		int
+	Cursor		string
+	Search		string
+	MinPrice	*float64
+	MaxPrice	*float64
+	UserID		*uuid.UUID
+	Sort		string
+	Order		string
+}
+
+type PageMeta struct {
+	Total		int64	`json:"total"`
+	Page		int		`json:"page,omitempty"`
+	Limit		int		`json:"limit"`
+	HasMore		bool	`json:"hasMore"`
+	NextCursor	string	`json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/dto"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+)
+
+type APIKeyHandler struct {
+	Service services.APIKeyService
+}
+
+func NewAPIKeyHandler(service services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{Service: service}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var request dto.CreateAPIKeyRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	key, plain, err := h.Service.CreateAPIKey(c.Context(), userID, request.Name, request.Scopes, request.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	resp := dto.CreatedAPIKeyResponse{
+		APIKeyResponse: toAPIKeyResponse(key),
+		Key: plain,
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"data": resp})
+}
+
+func (h *APIKeyHandler) ListAPIKeys(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	keys, err := h.Service.ListAPIKeys(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		resp = append(resp, toAPIKeyResponse(&keys[i]))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": resp})
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Service.RevokeAPIKey(c.Context(), userID, id); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func toAPIKeyResponse(key *models.APIKey) dto.APIKeyResponse {
+	return dto.APIKeyResponse{
+		ID: key.ID.String(),
+		Name: key.Name,
+		Prefix: key.Prefix,
+		Scopes: key.Scopes,
+		ExpiresAt: key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt: key.CreatedAt,
+	}
+}
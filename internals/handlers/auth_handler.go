@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/dto"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
@@ -11,50 +13,123 @@ import (
 
 type AuthHandler struct {
 	Service services.AuthService
+	Config	*config.Config
 }
 
-func NewAuthService(service services.AuthService) *AuthHandler {
-	return &AuthHandler{Service: service}
+func NewAuthService(service services.AuthService, cfg *config.Config) *AuthHandler {
+	return &AuthHandler{Service: service, Config: cfg}
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var request dto.LoginRequest
 
-	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request!"})
+	if err := bind(c, &request); err != nil {
+		return err
 	}
 
-	accessToken, refreshToken, err := h.Service.Login(c.Context(), request.Email, request.Password)
+	result, err := h.Service.Login(services.WithClientIP(c.Context(), c.IP()), request.Email, request.Password)
 
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	c.Cookie(&fiber.Cookie{
-		Name: "refreshToken",
-		Value: refreshToken,
-		Path: "/",
-		HTTPOnly: true,
-		Secure: true,
-		SameSite: "None",
-		Expires:  time.Now().AddDate(0, 0, 7),
-	})
+	if result.MFAToken != "" {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": dto.LoginResponse{
+			MFARequired: true,
+			MFAToken: result.MFAToken,
+		}})
+	}
+
+	h.setRefreshCookie(c, result.RefreshToken)
 
 	loginResp := dto.LoginResponse{
-		AccessToken: accessToken,
+		AccessToken: result.AccessToken,
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": loginResp})
 }
 
+// VerifyMFA exchanges the token of a login waiting for the second factor and
+// a TOTP or recovery code for the access and refresh tokens.
+func (h *AuthHandler) VerifyMFA(c *fiber.Ctx) error {
+	var request dto.MFAVerifyRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	result, err := h.Service.VerifyMFA(services.WithClientIP(c.Context(), c.IP()), request.MFAToken, request.Code)
+	if err != nil {
+		return err
+	}
+
+	h.setRefreshCookie(c, result.RefreshToken)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": dto.LoginResponse{AccessToken: result.AccessToken}})
+}
+
+func (h *AuthHandler) EnrollMFA(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	enrollment, err := h.Service.EnrollMFA(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	resp := dto.MFAEnrollResponse{
+		Secret: enrollment.Secret,
+		URI: enrollment.URI,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": resp})
+}
+
+func (h *AuthHandler) ConfirmMFA(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var request dto.MFACodeRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	codes, err := h.Service.ConfirmMFA(c.Context(), userID, request.Code)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": fiber.Map{"recoveryCodes": codes}})
+}
+
+func (h *AuthHandler) DisableMFA(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var request dto.MFACodeRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	if err := h.Service.DisableMFA(c.Context(), userID, request.Code); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	var request dto.RegisterRequest
 
-	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request!"})
-	}
-	
-	if request.Password != request.ConfPassword {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "password and confirm password not match"})
+	if err := bind(c, &request); err != nil {
+		return err
 	}
 
 	body := models.User{
@@ -66,7 +141,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	err := h.Service.Register(c.Context(), &body)
 
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	resp := dto.RegisterResponse{
@@ -80,41 +155,226 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 }
 
 func (h *AuthHandler) Me(c *fiber.Ctx) error {
-	userIDRaw := c.Locals("user_id")
-	userID, ok := userIDRaw.(string)
-
-	if !ok || userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized or userID not found in context",
-		})
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
 	}
 
-	user, err := h.Service.Me(c.Context(), userID)
+	user, err := h.Service.Me(c.Context(), userID.String())
 
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	resp := struct{
-		ID    string `json:"id"`
-		Name  string `json:"name"`
-		Email string `json:"email"`
+		ID            string   `json:"id"`
+		Name          string   `json:"name"`
+		Email         string   `json:"email"`
+		Roles         []string `json:"roles"`
+		EmailVerified bool     `json:"emailVerified"`
+		MFAEnabled    bool     `json:"mfaEnabled"`
 	}{
 		ID: user.ID.String(),
 		Name: user.Name,
 		Email: user.Email,
+		Roles: user.RoleNames(),
+		EmailVerified: user.EmailVerified(),
+		MFAEnabled: user.MFAEnabled(),
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": resp})
+}
+
+func (h *AuthHandler) Roles(c *fiber.Ctx) error {
+	roles, err := h.Service.Roles(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": roles})
+}
+
+// Unlock lets an administrator clear the lockout of an account after too
+// many failed logins.
+func (h *AuthHandler) Unlock(c *fiber.Ctx) error {
+	userID, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Service.Unlock(c.Context(), userID); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *AuthHandler) AssignRoles(c *fiber.Ctx) error {
+	userID, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	var request dto.AssignRolesRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	user, err := h.Service.AssignRoles(c.Context(), userID, request.Roles)
+	if err != nil {
+		return err
+	}
+
+	resp := dto.UserRolesResponse{
+		ID: user.ID.String(),
+		Roles: user.RoleNames(),
+		Permissions: user.PermissionNames(),
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": resp})
 }
 
 func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
-	refreshToken := c.Cookies("refreshToken")
+	refreshToken := c.Cookies(refreshCookieName)
 
-	accessToken, err := h.Service.Refresh(c.Context(), refreshToken)
+	accessToken, newRefreshToken, err := h.Service.Refresh(c.Context(), refreshToken)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		if errors.Is(err, services.ErrRefreshTokenReused) {
+			clearRefreshCookie(c)
+		}
+		return err
 	}
 
+	h.setRefreshCookie(c, newRefreshToken)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": fiber.Map{"accessToken": accessToken}})
-}
\ No newline at end of file
+}
+
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	refreshToken := c.Cookies(refreshCookieName)
+
+	if refreshToken != "" {
+		err := h.Service.Logout(c.Context(), refreshToken)
+		if err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+			return err
+		}
+	}
+
+	clearRefreshCookie(c)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ForgotPassword always answers 202, whether or not the email is registered.
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var request dto.ForgotPasswordRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	if err := h.Service.ForgotPassword(c.Context(), request.Email); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": fiber.Map{
+		"message": "if the email is registered, a reset link has been sent",
+	}})
+}
+
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var request dto.ResetPasswordRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	if err := h.Service.ResetPassword(c.Context(), request.Token, request.Password); err != nil {
+		return err
+	}
+
+	clearRefreshCookie(c)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": fiber.Map{"message": "password has been reset"}})
+}
+
+// ChangePassword sets a new password for the logged in user. The refresh
+// cookie is cleared since every session is logged out.
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var request dto.ChangePasswordRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	if err := h.Service.ChangePassword(c.Context(), userID, request.CurrentPassword, request.Password); err != nil {
+		return err
+	}
+
+	clearRefreshCookie(c)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": fiber.Map{"message": "password has been changed"}})
+}
+
+// VerifyEmail is the target of the link in the verification email.
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return services.Validation(services.FieldError{Field: "token", Rule: "required", Message: "token is required"})
+	}
+
+	if err := h.Service.VerifyEmail(c.Context(), token); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": fiber.Map{"message": "email has been verified"}})
+}
+
+// ResendVerification always answers 202, like ForgotPassword.
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	var request dto.ResendVerificationRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	if err := h.Service.ResendVerification(c.Context(), request.Email); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": fiber.Map{
+		"message": "if the email is registered and not verified yet, a verification link has been sent",
+	}})
+}
+
+const refreshCookieName = "refreshToken"
+
+func (h *AuthHandler) setRefreshCookie(c *fiber.Ctx, refreshToken string) {
+	c.Cookie(&fiber.Cookie{
+		Name: refreshCookieName,
+		Value: refreshToken,
+		Path: "/",
+		HTTPOnly: true,
+		Secure: true,
+		SameSite: "None",
+		Expires:  time.Now().Add(h.Config.JWT.RefreshTTL),
+	})
+}
+
+func clearRefreshCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name: refreshCookieName,
+		Value: "",
+		Path: "/",
+		HTTPOnly: true,
+		Secure: true,
+		SameSite: "None",
+		Expires:  time.Unix(0, 0),
+	})
+}
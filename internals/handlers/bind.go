@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON name, which is what clients send.
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	return v
+}
+
+// bind parses the request body into out and enforces its validate tags.
+// Rejected fields are returned as services.ErrValidation.
+func bind(c *fiber.Ctx, out interface{}) error {
+	if err := c.BodyParser(out); err != nil {
+		return services.ErrInvalidRequest
+	}
+
+	return validateStruct(out)
+}
+
+func validateStruct(value interface{}) error {
+	err := validate.Struct(value)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return services.Internal(err)
+	}
+
+	fields := make([]services.FieldError, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		fields = append(fields, services.FieldError{
+			Field: fieldPath(fieldErr),
+			Rule: fieldErr.Tag(),
+			Message: fieldMessage(fieldErr),
+		})
+	}
+
+	return services.Validation(fields...)
+}
+
+// fieldPath drops the struct name from the namespace, e.g. "roles[0]".
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, found := strings.Cut(fieldErr.Namespace(), ".")
+	if !found {
+		return fieldErr.Field()
+	}
+	return path
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	isString := fieldErr.Kind() == reflect.String
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", param)
+		}
+		if fieldErr.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at least %s items", param)
+		}
+		return "must be at least " + param
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", param)
+		}
+		return "must be at most " + param
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be greater than or equal to " + param
+	case "eqfield":
+		return "must match " + jsonName(param)
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(param, " ", ", ")
+	case "uuid", "uuid4":
+		return "must be a valid uuid"
+	default:
+		return "failed the " + fieldErr.Tag() + " rule"
+	}
+}
+
+// jsonName turns a Go field name used in a rule parameter into its JSON
+// form, e.g. "Password" becomes "password".
+func jsonName(field string) string {
+	if field == "" {
+		return field
+	}
+	return strings.ToLower(field[:1]) + field[1:]
+}
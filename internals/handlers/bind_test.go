@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/dto"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStruct_RegisterRequest(t *testing.T) {
+	err := validateStruct(&dto.RegisterRequest{
+		Name: "T",
+		Email: "not-an-email",
+		Password: "1",
+		ConfPassword: "2",
+	})
+
+	assert.ErrorIs(t, err, services.ErrValidation)
+
+	fields := map[string]string{}
+	for _, field := range services.AsError(err).Fields {
+		fields[field.Field] = field.Message
+	}
+
+	assert.Equal(t, "must be at least 2 characters", fields["name"])
+	assert.Equal(t, "must be a valid email address", fields["email"])
+	assert.Equal(t, "must be at least 8 characters", fields["password"])
+	assert.Equal(t, "must match password", fields["confPassword"])
+}
+
+func TestValidateStruct_ProductPatch(t *testing.T) {
+	negative := -10.0
+
+	assert.NoError(t, validateStruct(&dto.ProductPatchRequest{}))
+	assert.ErrorIs(t, validateStruct(&dto.ProductPatchRequest{Price: &negative}), services.ErrValidation)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+)
+
+// currentUserID reads the user_id local set by middlewares.JWTProtected.
+func currentUserID(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDstr, ok := c.Locals("user_id").(string)
+
+	if !ok || userIDstr == "" {
+		return uuid.Nil, services.ErrUnauthorized
+	}
+
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		return uuid.Nil, services.ErrUnauthorized
+	}
+
+	return userID, nil
+}
+
+// paramID parses the :id route parameter.
+func paramID(c *fiber.Ctx) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, services.ErrInvalidID
+	}
+
+	return id, nil
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document. Code is a stable, machine
+// readable identifier clients can switch on.
+type Problem struct {
+	Type		string	`json:"type"`
+	Title		string	`json:"title"`
+	Status		int		`json:"status"`
+	Detail		string	`json:"detail,omitempty"`
+	Instance	string	`json:"instance,omitempty"`
+	Code		string	`json:"code"`
+
+	Errors		[]services.FieldError	`json:"errors,omitempty"`
+}
+
+var kindStatus = map[services.Kind]int{
+	services.KindInvalid:			fiber.StatusBadRequest,
+	services.KindUnauthorized:		fiber.StatusUnauthorized,
+	services.KindForbidden:			fiber.StatusForbidden,
+	services.KindNotFound:			fiber.StatusNotFound,
+	services.KindConflict:			fiber.StatusConflict,
+	services.KindTooManyRequests:	fiber.StatusTooManyRequests,
+	services.KindInternal:			fiber.StatusInternalServerError,
+}
+
+// ErrorHandler renders every error returned by a handler as problem+json.
+// Errors that are not services.Error are logged and hidden behind a generic
+// internal error.
+func ErrorHandler(logger *slog.Logger) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		return renderError(c, err, logger)
+	}
+}
+
+func renderError(c *fiber.Ctx, err error, logger *slog.Logger) error {
+	problem := Problem{Instance: c.OriginalURL()}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		problem.Status = fiberErr.Code
+		problem.Detail = fiberErr.Message
+		problem.Code = strings.ReplaceAll(strings.ToLower(http.StatusText(fiberErr.Code)), " ", "_")
+	} else {
+		domainErr := services.AsError(err)
+
+		status, ok := kindStatus[domainErr.Kind]
+		if !ok {
+			status = fiber.StatusInternalServerError
+		}
+
+		if status == fiber.StatusInternalServerError {
+			logger.ErrorContext(c.Context(), "internal error", "method", c.Method(), "path", c.Path(), "error", err.Error())
+		}
+
+		problem.Status = status
+		problem.Detail = domainErr.Message
+		problem.Code = domainErr.Code
+		problem.Errors = domainErr.Fields
+
+		if domainErr.RetryAfter > 0 {
+			seconds := int(math.Ceil(domainErr.RetryAfter.Seconds()))
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+		}
+	}
+
+	// Read by middlewares.RequestLogger.
+	c.Locals("error_code", problem.Code)
+
+	problem.Title = http.StatusText(problem.Status)
+	problem.Type = "/problems/" + strings.ReplaceAll(problem.Code, "_", "-")
+
+	return c.Status(problem.Status).JSON(problem, problemContentType)
+}
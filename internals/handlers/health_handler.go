@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/health"
+)
+
+// HealthHandler answers the liveness and readiness probes of the platform.
+// Both serve their document as is, without the data envelope, and are
+// never cached.
+type HealthHandler struct {
+	Checks	*health.Registry
+	Logger	*slog.Logger
+}
+
+func NewHealthHandler(checks *health.Registry, logger *slog.Logger) *HealthHandler {
+	return &HealthHandler{Checks: checks, Logger: logger}
+}
+
+// Live only tells the process is up, it checks no dependency.
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": health.StatusUp})
+}
+
+// Ready runs every registered check and answers 503 when any is down. The
+// probe is public, so why a check is down is only logged.
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	// A check that ignores its timeout outlives the request, so it must not
+	// hold the fasthttp context, which is reused for the next request.
+	report := h.Checks.Run(c.UserContext())
+	for name, result := range report.Checks {
+		if result.Status != health.StatusUp {
+			h.Logger.WarnContext(c.Context(), "readiness check down", "check", name, "error", result.Error)
+		}
+	}
+
+	status := fiber.StatusOK
+	if report.Status != health.StatusUp {
+		status = fiber.StatusServiceUnavailable
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(report)
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/jwt"
+)
+
+// KeysHandler publishes the public keys other services use to verify the
+// access tokens.
+type KeysHandler struct {
+	Tokens *jwt.Manager
+}
+
+func NewKeysHandler(tokens *jwt.Manager) *KeysHandler {
+	return &KeysHandler{Tokens: tokens}
+}
+
+// JWKS serves the key set as is, without the data envelope, because
+// verifiers expect the RFC 7517 document.
+func (h *KeysHandler) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(h.Tokens.JWKS())
+}
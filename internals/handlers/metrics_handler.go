@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/metrics"
+)
+
+// MetricsHandler exposes the metrics to Prometheus.
+type MetricsHandler struct {
+	Metrics	*metrics.Metrics
+	serve	fiber.Handler
+}
+
+func NewMetricsHandler(m *metrics.Metrics) *MetricsHandler {
+	return &MetricsHandler{Metrics: m, serve: adaptor.HTTPHandler(m.Handler())}
+}
+
+// Scrape serves the metrics in the Prometheus text format, without the data
+// envelope.
+func (h *MetricsHandler) Scrape(c *fiber.Ctx) error {
+	return h.serve(c)
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/dto"
@@ -17,37 +20,48 @@ func NewProductHandler(service services.ProductService) *ProductHandler {
 }
 
 func (h *ProductHandler) GetProducts(c *fiber.Ctx) error {
-	products, err := h.Services.GetProducts(c.Context())
+	query, err := parseProductQuery(c)
+	if err != nil {
+		return err
+	}
+
+	products, meta, err := h.Services.GetProducts(c.Context(), query)
 
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": products})
+	if products == nil {
+		products = []dto.ProductResponse{}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": products, "meta": meta})
 }
 
-func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
-	userIDRaw := c.Locals("user_id")
-	userIDstr, ok := userIDRaw.(string)
+func (h *ProductHandler) GetProduct(c *fiber.Ctx) error {
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
 
-	if !ok || userIDstr == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized or userID not found in context",
-		})
+	product, err := h.Services.GetProduct(c.Context(), id)
+	if err != nil {
+		return err
 	}
-	
-	userID, err := uuid.Parse(userIDstr)
 
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": toProductResponse(product)})
+}
+
+func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "user id is not valid uuid",
-		})
+		return err
 	}
 
 	var product dto.ProductRequest
 	
-	if err := c.BodyParser(&product); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	if err := bind(c, &product); err != nil {
+		return err
 	}
 
 	newProduct := models.Product{
@@ -57,15 +71,125 @@ func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 	}
 
 	if err := h.Services.CreateProduct(c.Context(), &newProduct); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"data": toProductResponse(&newProduct)})
+}
+
+// ReplaceProduct handles PUT, every field of the product is overwritten.
+func (h *ProductHandler) ReplaceProduct(c *fiber.Ctx) error {
+	var request dto.ProductRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	return h.updateProduct(c, dto.ProductPatchRequest{
+		Name: &request.Name,
+		Price: &request.Price,
+	})
+}
+
+// PatchProduct handles PATCH, only the fields sent in the body are changed.
+func (h *ProductHandler) PatchProduct(c *fiber.Ctx) error {
+	var request dto.ProductPatchRequest
+
+	if err := bind(c, &request); err != nil {
+		return err
+	}
+
+	return h.updateProduct(c, request)
+}
+
+func (h *ProductHandler) DeleteProduct(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Services.DeleteProduct(c.Context(), id, userID); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *ProductHandler) updateProduct(c *fiber.Ctx, input dto.ProductPatchRequest) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
 	}
 
-	productResp := dto.ProductResponse{
-		ID: newProduct.ID,
-		Name: newProduct.Name,
-		Price: newProduct.Price,
-		CreatedAt: newProduct.CreatedAt,
+	id, err := paramID(c)
+	if err != nil {
+		return err
+	}
+
+	product, err := h.Services.UpdateProduct(c.Context(), id, userID, input)
+	if err != nil {
+		return err
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"data": productResp})
-}
\ No newline at end of file
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": toProductResponse(product)})
+}
+
+func invalidQuery(message string) error {
+	return services.NewError(services.KindInvalid, "invalid_query", message)
+}
+
+func toProductResponse(product *models.Product) dto.ProductResponse {
+	return dto.ProductResponse{
+		ID: product.ID,
+		Name: product.Name,
+		Price: product.Price,
+		UserID: product.UserID,
+		CreatedAt: product.CreatedAt,
+		UpdatedAt: product.UpdatedAt,
+	}
+}
+
+// parseProductQuery reads the listing parameters:
+// page, limit, cursor, q, minPrice, maxPrice, userId, sort and order.
+func parseProductQuery(c *fiber.Ctx) (dto.ProductQuery, error) {
+	query := dto.ProductQuery{
+		Page: c.QueryInt("page", 1),
+		Limit: c.QueryInt("limit", services.DefaultProductLimit),
+		Cursor: c.Query("cursor"),
+		Search: c.Query("q"),
+		Sort: c.Query("sort"),
+		Order: strings.ToLower(c.Query("order", "desc")),
+	}
+
+	if query.Order != "asc" && query.Order != "desc" {
+		return query, invalidQuery("order must be asc or desc")
+	}
+
+	for name, target := range map[string]**float64{"minPrice": &query.MinPrice, "maxPrice": &query.MaxPrice} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return query, invalidQuery(name + " must be a number")
+		}
+		*target = &value
+	}
+
+	if raw := c.Query("userId"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			return query, invalidQuery("userId is not valid uuid")
+		}
+		query.UserID = &userID
+	}
+
+	return query, nil
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// productApp serves the product routes as userID, with one product owned by
+// owner.
+func productApp(t *testing.T, userID, owner uuid.UUID) (*fiber.App, uuid.UUID) {
+	repos := repository.NewMemoryRepositories()
+	require.NoError(t, repos.Users.Create(context.Background(), &models.User{ID: owner, Name: "Owner", Email: "owner@dev.com"}))
+
+	product := &models.Product{ID: uuid.New(), Name: "Product A", Price: 1000, UserID: owner}
+	require.NoError(t, repos.Products.Create(context.Background(), product))
+
+	service := services.NewProductService(repos.Products, repos.Users, false, logging.Discard(), tracing.Noop())
+	h := NewProductHandler(service)
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(logging.Discard())})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID.String())
+		return c.Next()
+	})
+	app.Get("/products/:id", h.GetProduct)
+	app.Put("/products/:id", h.ReplaceProduct)
+	app.Patch("/products/:id", h.PatchProduct)
+	app.Delete("/products/:id", h.DeleteProduct)
+
+	return app, product.ID
+}
+
+// sendProblem sends the request and decodes the problem+json answer.
+func sendProblem(t *testing.T, app *fiber.App, method, path, body string) (int, Problem) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+
+	assert.Equal(t, problemContentType, resp.Header.Get(fiber.HeaderContentType))
+
+	var problem Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	return resp.StatusCode, problem
+}
+
+func TestProductHandler_NotOwner(t *testing.T) {
+	app, id := productApp(t, uuid.New(), uuid.New())
+	path := "/products/" + id.String()
+
+	for _, request := range []struct{ method, body string }{
+		{http.MethodPut, `{"name":"Product B","price":2000}`},
+		{http.MethodPatch, `{"price":2000}`},
+		{http.MethodDelete, ""},
+	} {
+		status, problem := sendProblem(t, app, request.method, path, request.body)
+		assert.Equal(t, http.StatusForbidden, status, request.method)
+		assert.Equal(t, http.StatusForbidden, problem.Status, request.method)
+		assert.Equal(t, "not_product_owner", problem.Code, request.method)
+		assert.Equal(t, "/problems/not-product-owner", problem.Type, request.method)
+		assert.Equal(t, path, problem.Instance, request.method)
+	}
+}
+
+func TestProductHandler_NotFound(t *testing.T) {
+	owner := uuid.New()
+	app, _ := productApp(t, owner, owner)
+	path := "/products/" + uuid.NewString()
+
+	for _, request := range []struct{ method, body string }{
+		{http.MethodGet, ""},
+		{http.MethodPut, `{"name":"Product B","price":2000}`},
+		{http.MethodPatch, `{"price":2000}`},
+		{http.MethodDelete, ""},
+	} {
+		status, problem := sendProblem(t, app, request.method, path, request.body)
+		assert.Equal(t, http.StatusNotFound, status, request.method)
+		assert.Equal(t, http.StatusNotFound, problem.Status, request.method)
+		assert.Equal(t, "product_not_found", problem.Code, request.method)
+		assert.Equal(t, "Not Found", problem.Title, request.method)
+	}
+}
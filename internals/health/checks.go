@@ -0,0 +1,32 @@
+package health
+
+import (
+	"context"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/db"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/jwt"
+	"gorm.io/gorm"
+)
+
+// Database pings the connection pool of conn.
+func Database(conn *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := conn.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Migrations fails while a migration is not applied, see db.Migrator.Current.
+func Migrations(migrator *db.Migrator) Check {
+	return migrator.Current
+}
+
+// SigningKeys fails when tokens can not sign and validate an access token.
+func SigningKeys(tokens *jwt.Manager) Check {
+	return func(ctx context.Context) error {
+		return tokens.Check()
+	}
+}
@@ -0,0 +1,121 @@
+// Package health runs the readiness checks of the application. Every
+// dependency registers a named Check, so a new one only has to call
+// Register.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds every check, a dependency that hangs counts as down.
+const DefaultTimeout = 2 * time.Second
+
+// Statuses of a Report and of every Result.
+const (
+	StatusUp	= "up"
+	StatusDown	= "down"
+)
+
+// Check returns nil when the dependency can serve requests.
+type Check func(ctx context.Context) error
+
+// Result is served to anyone asking /readyz, so Error is left out of the
+// JSON: driver errors and migration names are for the logs only.
+type Result struct {
+	Status		string	`json:"status"`
+	Error		string	`json:"-"`
+	DurationMs	float64	`json:"durationMs"`
+}
+
+// Report is up only when every check is.
+type Report struct {
+	Status	string				`json:"status"`
+	Checks	map[string]Result	`json:"checks"`
+}
+
+type Registry struct {
+	Timeout	time.Duration
+
+	mu		sync.RWMutex
+	checks	map[string]Check
+}
+
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{Timeout: timeout, checks: map[string]Check{}}
+}
+
+// Register adds check under name, replacing a check of the same name.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks[name] = check
+}
+
+// Run runs every check concurrently, each bounded by Timeout.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make(map[string]Check, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := r.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// run waits for check at most Timeout, even when check ignores ctx.
+func (r *Registry) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- fmt.Errorf("check panicked: %v", recovered)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", r.Timeout)
+	}
+
+	result := Result{Status: StatusUp, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Run(t *testing.T) {
+	registry := NewRegistry(50 * time.Millisecond)
+	registry.Register("database", func(ctx context.Context) error { return nil })
+	registry.Register("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUp, report.Checks["database"].Status)
+	assert.Equal(t, StatusDown, report.Checks["cache"].Status)
+	assert.Equal(t, "connection refused", report.Checks["cache"].Error)
+
+	// A check registered again under the same name replaces the old one.
+	registry.Register("cache", func(ctx context.Context) error { return nil })
+	assert.Equal(t, StatusUp, registry.Run(context.Background()).Status)
+}
+
+func TestRegistry_RunTimeout(t *testing.T) {
+	registry := NewRegistry(20 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+
+	// The check ignores its context, Run must not wait for it.
+	registry.Register("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Contains(t, report.Checks["stuck"].Error, "timed out")
+}
+
+func TestRegistry_RunPanic(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("broken", func(ctx context.Context) error { panic("nil pool") })
+
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, StatusDown, report.Checks["broken"].Status)
+	assert.Contains(t, report.Checks["broken"].Error, "nil pool")
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// SlowQuery is the duration above which a query is logged as a warning.
+const SlowQuery = 200 * time.Millisecond
+
+// gormLogger sends the GORM logs to slog. Statements are logged at debug
+// level without their parameters, which may be password or token hashes.
+// Missing records are expected and not logged as errors.
+type gormLogger struct {
+	logger *slog.Logger
+}
+
+// NewGormLogger returns the logger to set as gorm.Config.Logger.
+func NewGormLogger(logger *slog.Logger) gormlogger.Interface {
+	return &gormLogger{logger: logger.With("component", "gorm")}
+}
+
+func (l *gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+
+	level := slog.LevelDebug
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level = slog.LevelError
+	case elapsed > SlowQuery:
+		level = slog.LevelWarn
+	}
+
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	l.logger.LogAttrs(ctx, level, "query", attrs...)
+}
+
+// ParamsFilter keeps the parameters out of the logged statements.
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,92 @@
+// Package logging builds the structured logger of the application. Records
+// logged with a request context carry its request_id and user_id, and values
+// of sensitive keys such as passwords, tokens and cookies are redacted.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched against attribute keys in lower case, any key
+// containing one of them is redacted.
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "cookie", "api_key", "apikey", "x-api-key"}
+
+// New returns a logger writing to w in the configured format and level.
+func New(cfg config.LogConfig, w io.Writer) *slog.Logger {
+	options := &slog.HandlerOptions{
+		Level: parseLevel(cfg.Level),
+		ReplaceAttr: redact,
+	}
+
+	var handler slog.Handler
+	if cfg.Format == config.LogText {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// Discard returns a logger that drops every record, for tests.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+func parseLevel(level string) slog.Level {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return parsed
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if Sensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+	return attr
+}
+
+// Sensitive reports whether values named key must not be logged.
+func Sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// contextHandler adds the request_id and user_id locals of the request, see
+// middlewares.RequestLogger, to every record logged with its context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		for _, key := range []string{"request_id", "user_id"} {
+			if value, ok := ctx.Value(key).(string); ok && value != "" {
+				record.AddAttrs(slog.String(key, value))
+			}
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_RedactsSensitiveKeys(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(config.LogConfig{Level: "info", Format: config.LogJSON}, &out)
+
+	logger.Info("login",
+		"email", "taufik@dev.com",
+		"password", "rahasia123",
+		"refresh_token", "eyJhbGciOi",
+		"Authorization", "Bearer eyJhbGciOi",
+		"Set-Cookie", "refresh_token=eyJhbGciOi",
+		"X-API-Key", "ak_live_123",
+	)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "taufik@dev.com", record["email"])
+	for _, key := range []string{"password", "refresh_token", "Authorization", "Set-Cookie", "X-API-Key"} {
+		assert.Equal(t, redacted, record[key], key)
+	}
+	assert.NotContains(t, out.String(), "rahasia123")
+	assert.NotContains(t, out.String(), "eyJhbGciOi")
+}
+
+func TestNew_AddsRequestLocals(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(config.LogConfig{Level: "debug", Format: config.LogJSON}, &out)
+
+	ctx := context.WithValue(context.Background(), "request_id", "trace-123")
+	ctx = context.WithValue(ctx, "user_id", "4b1c0e9e-3f53-4c43-9a2c-5d8b0e1f7a10")
+	logger.With("component", "test").DebugContext(ctx, "hello")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "trace-123", record["request_id"])
+	assert.Equal(t, "4b1c0e9e-3f53-4c43-9a2c-5d8b0e1f7a10", record["user_id"])
+	assert.Equal(t, "test", record["component"])
+}
+
+func TestNew_Level(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(config.LogConfig{Level: "warn", Format: config.LogText}, &out)
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	assert.NotContains(t, out.String(), "hidden")
+	assert.Contains(t, out.String(), "msg=shown")
+}
@@ -0,0 +1,71 @@
+// Package mail sends the transactional emails of the application. Sender is
+// the extension point, LogSender and FileSender are local stand-ins until a
+// real provider is configured.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+)
+
+type Message struct {
+	From	string
+	To		string
+	Subject	string
+	Body	string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender records every message in Logger without delivering it. The body
+// carries reset and verification links, so only the recipient and subject
+// are logged; use FileSender to read the links locally.
+type LogSender struct {
+	Logger *slog.Logger
+}
+
+func (s LogSender) Send(ctx context.Context, msg Message) error {
+	s.Logger.InfoContext(ctx, "mail", "to", msg.To, "subject", msg.Subject)
+	return nil
+}
+
+// FileSender stores every message as an .eml file in Dir.
+type FileSender struct {
+	Dir string
+}
+
+func (s FileSender) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&content, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&content, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&content, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	content.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	content.WriteString(msg.Body)
+
+	return os.WriteFile(filepath.Join(s.Dir, name), []byte(content.String()), 0o600)
+}
+
+// NewSender returns the sender selected by cfg.Driver.
+func NewSender(cfg config.MailConfig, logger *slog.Logger) Sender {
+	if cfg.Driver == config.MailFile {
+		return FileSender{Dir: cfg.Dir}
+	}
+	return LogSender{Logger: logger}
+}
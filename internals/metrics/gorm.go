@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// startedKey holds the start of a query in the gorm.DB instance settings.
+const startedKey = "metrics:started"
+
+// InstrumentDB times every query of db in DBQueries and exports the stats
+// of its connection pool as go_sql_* metrics labeled db_name=name.
+func (m *Metrics) InstrumentDB(db *gorm.DB, name string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	if err := m.Registry.Register(collectors.NewDBStatsCollector(sqlDB, name)); err != nil {
+		return err
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", startQuery),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", m.endQuery("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", startQuery),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", m.endQuery("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", startQuery),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", m.endQuery("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", startQuery),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", m.endQuery("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", startQuery),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", m.endQuery("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", startQuery),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", m.endQuery("raw")),
+	)
+}
+
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(startedKey, time.Now())
+}
+
+func (m *Metrics) endQuery(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startedKey)
+		if !ok {
+			return
+		}
+		started, _ := value.(time.Time)
+
+		status := "ok"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = "error"
+		}
+
+		m.DBQueries.WithLabelValues(operation, db.Statement.Table, status).Observe(time.Since(started).Seconds())
+	}
+}
@@ -0,0 +1,92 @@
+// Package metrics collects the Prometheus metrics of the application: HTTP
+// requests, login outcomes and database queries. Every Metrics has its own
+// registry, so several apps, e.g. in tests, do not collide.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Login results counted by Login.
+const (
+	LoginSuccess		= "success"
+	LoginMFARequired	= "mfa_required"
+	LoginFailure		= "failure"
+	LoginLocked			= "locked"
+	LoginError			= "error"
+)
+
+// Login steps counted by Login, the password and the second factor.
+const (
+	StepPassword	= "password"
+	StepMFA			= "mfa"
+)
+
+type Metrics struct {
+	Registry		*prometheus.Registry
+
+	HTTPRequests	*prometheus.CounterVec
+	HTTPDuration	*prometheus.HistogramVec
+	Logins			*prometheus.CounterVec
+	DBQueries		*prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		HTTPDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "http_request_duration_seconds",
+			Help: "Latency of HTTP requests by method, route template and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		Logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_logins_total",
+			Help: "Login attempts by step and result.",
+		}, []string{"step", "result"}),
+		DBQueries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "db_query_duration_seconds",
+			Help: "Duration of database queries by operation, table and status.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table", "status"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequests,
+		m.HTTPDuration,
+		m.Logins,
+		m.DBQueries,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// ObserveRequest counts one handled request.
+func (m *Metrics) ObserveRequest(method, route, status string, elapsed time.Duration) {
+	m.HTTPRequests.WithLabelValues(method, route, status).Inc()
+	m.HTTPDuration.WithLabelValues(method, route, status).Observe(elapsed.Seconds())
+}
+
+// Login counts one login attempt. It does nothing on a nil Metrics, so
+// services work without metrics.
+func (m *Metrics) Login(step, result string) {
+	if m == nil {
+		return
+	}
+	m.Logins.WithLabelValues(step, result).Inc()
+}
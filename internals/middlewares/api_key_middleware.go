@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/jwt"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// Authenticate accepts an X-API-Key header or a Bearer access token and sets
+// the same user_id, roles and permissions locals as JWTProtected. Requests
+// with an API key get no roles, only the scopes of the key, and the
+// api_key_id local.
+func Authenticate(tokens *jwt.Manager, keys services.APIKeyService) fiber.Handler {
+	jwtProtected := JWTProtected(tokens)
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(APIKeyHeader)
+		if key == "" {
+			return jwtProtected(c)
+		}
+
+		identity, err := keys.Authenticate(c.Context(), key)
+		if err != nil {
+			return err
+		}
+
+		c.Locals("user_id", identity.UserID.String())
+		c.Locals("roles", []string{})
+		c.Locals("permissions", identity.Permissions)
+		c.Locals("api_key_id", identity.KeyID.String())
+
+		return c.Next()
+	}
+}
@@ -1,45 +1,32 @@
 package middlewares
 
 import (
-	"os"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/jwt"
 )
 
-func JWTProtected() fiber.Handler {
+func JWTProtected(tokens *jwt.Manager) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or malformed token"})
+			return services.ErrMissingToken
 		}
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.ErrUnauthorized
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired token"})
-		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
-		}
+		claims, err := tokens.ValidateAccessToken(tokenStr)
 
-		userID := claims["user_id"]
-		if userID == nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user_id in token"})
+		if err != nil {
+			return services.ErrInvalidToken.Wrap(err)
 		}
 
-		c.Locals("user_id", userID)
+		c.Locals("user_id", claims.Subject)
+		c.Locals("roles", claims.Roles)
+		c.Locals("permissions", claims.Permissions)
 
 		return c.Next()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/metrics"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+)
+
+// Metrics counts and times every request by its route template, so
+// /api/products/:id is one series whatever the id. It must run before
+// RequestLogger, which renders the errors, to see the status sent.
+func Metrics(m *metrics.Metrics) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// Only reached when no RequestLogger renders the error.
+			status = fiber.StatusInternalServerError
+		}
+
+		// The labels outlive the request, the method must not share its
+		// reused buffer.
+		m.ObserveRequest(strings.Clone(c.Method()), c.Route().Path, strconv.Itoa(status), time.Since(start))
+
+		return err
+	}
+}
+
+// MetricsToken allows the request only with "Authorization: Bearer token".
+func MetricsToken(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			return services.ErrMissingToken
+		}
+
+		given := strings.TrimPrefix(authHeader, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return services.ErrInvalidToken
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
+)
+
+// RequirePermission allows the request only when the access token grants
+// every listed permission. It must run after JWTProtected.
+func RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		granted, _ := c.Locals("permissions").([]string)
+
+		for _, permission := range permissions {
+			if !slices.Contains(granted, permission) {
+				return services.NewError(services.KindForbidden, "missing_permission", "Missing permission "+permission)
+			}
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// RequestLogger gives every request an ID, taken from X-Request-ID when the
+// client or a proxy sent a sane one, and returns it in the same header. The
+// ID is stored in the request_id local, so everything logged with
+// c.Context() carries it. Once the request is handled, one record is logged
+// with the method, route, status, latency, the user_id local of
+// authenticated requests and the error code of failed ones.
+//
+// It must run before the middlewares that may fail: errors are rendered here
+// with the error handler of the app, so the logged status is the one sent.
+func RequestLogger(logger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		requestID := c.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Locals("request_id", requestID)
+		c.Set(RequestIDHeader, requestID)
+
+		if err := c.Next(); err != nil {
+			if err := c.App().Config().ErrorHandler(c, err); err != nil {
+				c.Status(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("route", c.Route().Path),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", c.IP()),
+		}
+		if code, ok := c.Locals("error_code").(string); ok {
+			attrs = append(attrs, slog.String("error_code", code))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		logger.LogAttrs(c.Context(), level, "request", attrs...)
+
+		return nil
+	}
+}
+
+// validRequestID accepts IDs of letters, digits and -_.: so a client cannot
+// inject anything into the logs or the response headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
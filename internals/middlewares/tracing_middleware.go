@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts the server span of every request, continuing the trace of
+// a W3C traceparent header, and stores it in the trace_span local for
+// tracing.Start. Like Metrics it must run before RequestLogger. Server errors
+// mark the span as failed, the error code of every failed request is
+// recorded.
+func Tracing(provider trace.TracerProvider) fiber.Handler {
+	tracer := tracing.Tracer(provider)
+
+	return func(c *fiber.Ctx) error {
+		ctx := tracing.Propagator.Extract(c.UserContext(), headerCarrier{c})
+
+		// Spans outlive the request, so nothing may share its reused buffers.
+		method := strings.Clone(c.Method())
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", method),
+				attribute.String("url.path", strings.Clone(c.Path())),
+				attribute.String("client.address", strings.Clone(c.IP())),
+			))
+		defer span.End()
+
+		c.SetUserContext(ctx)
+		c.Locals(tracing.SpanLocal, span)
+
+		err := c.Next()
+
+		route := c.Route().Path
+		status := c.Response().StatusCode()
+
+		span.SetName(method + " " + route)
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		if code, ok := c.Locals("error_code").(string); ok {
+			span.SetAttributes(attribute.String("error.type", code))
+		}
+		if err != nil || status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+
+		return err
+	}
+}
+
+// headerCarrier lets the propagator read the request headers.
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return strings.Clone(h.c.Get(key))
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	var keys []string
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey lets scripts authenticate as a user with the X-API-Key header. Only
+// the SHA-256 hash of the key is stored, Prefix is kept so users can tell
+// their keys apart. Scopes are the permissions the key may use, limited to
+// the permissions the user still has.
+type APIKey struct {
+	ID			uuid.UUID	`gorm:"type:uuid;primaryKey" json:"id"`
+	UserID		uuid.UUID	`gorm:"type:uuid;index" json:"userId"`
+	Name		string		`json:"name"`
+	Prefix		string		`json:"prefix"`
+	KeyHash		string		`gorm:"uniqueIndex" json:"-"`
+	Scopes		[]string	`gorm:"serializer:json" json:"scopes"`
+
+	ExpiresAt	*time.Time	`json:"expiresAt"`
+	LastUsedAt	*time.Time	`json:"lastUsedAt"`
+	RevokedAt	*time.Time	`json:"revokedAt"`
+	CreatedAt	time.Time	`json:"createdAt"`
+}
+
+// Active reports whether the key can still authenticate at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailVerificationToken stores the SHA-256 hash of a verification token sent
+// to the address of the user. A token can be used once, before ExpiresAt.
+type EmailVerificationToken struct {
+	ID			uuid.UUID	`gorm:"type:uuid;primaryKey" json:"id"`
+	UserID		uuid.UUID	`gorm:"type:uuid;index" json:"userId"`
+	TokenHash	string		`gorm:"uniqueIndex" json:"-"`
+
+	ExpiresAt	time.Time	`json:"expiresAt"`
+	UsedAt		*time.Time	`json:"usedAt"`
+	CreatedAt	time.Time	`json:"createdAt"`
+}
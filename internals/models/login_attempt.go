@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LoginAttempt counts the failed logins of one key, an account email or a
+// client IP address, and how long the key is locked out for.
+type LoginAttempt struct {
+	Key			string		`gorm:"primaryKey" json:"key"`
+	Failures	int			`json:"failures"`
+	LockedUntil	*time.Time	`json:"lockedUntil"`
+	UpdatedAt	time.Time	`gorm:"index" json:"updatedAt"`
+}
+
+// Locked reports whether the key is still locked out at now.
+func (a *LoginAttempt) Locked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
@@ -4,10 +4,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Product struct {
-	ID			uuid.UUID	`gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	ID			uuid.UUID	`gorm:"type:uuid;primaryKey" json:"id"`
 	Name		string		`json:"name"`
 	Price		float64		`json:"price"`
 
@@ -16,4 +17,12 @@ type Product struct {
 
 	CreatedAt	time.Time	`json:"createdAt"`
 	UpdatedAt	time.Time	`json:"updatedAt"`
-}
\ No newline at end of file
+}
+
+// BeforeCreate assigns the ID in Go so every database driver works the same.
+func (p *Product) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RateLimit is the state of one rate limited key, see ratelimit.State.
+type RateLimit struct {
+	Key			string		`gorm:"primaryKey"`
+	Value		float64
+	Previous	float64
+	At			time.Time
+	ExpiresAt	time.Time	`gorm:"index"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCode is a one time code that replaces a TOTP code when the user has
+// lost their authenticator. Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID			uuid.UUID	`gorm:"type:uuid;primaryKey" json:"id"`
+	UserID		uuid.UUID	`gorm:"type:uuid;index" json:"userId"`
+	CodeHash	string		`json:"-"`
+
+	UsedAt		*time.Time	`json:"usedAt"`
+	CreatedAt	time.Time	`json:"createdAt"`
+}
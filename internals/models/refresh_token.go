@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is the server side record of an issued refresh token. ID is
+// the jti claim of the token. Every rotation creates a new record in the same
+// family, so a reused token can revoke the whole login session.
+type RefreshToken struct {
+	ID				uuid.UUID	`gorm:"type:uuid;primaryKey" json:"id"`
+	UserID			uuid.UUID	`gorm:"type:uuid;index" json:"userId"`
+	FamilyID		uuid.UUID	`gorm:"type:uuid;index" json:"familyId"`
+	ReplacedByID	*uuid.UUID	`gorm:"type:uuid" json:"replacedById"`
+
+	ExpiresAt		time.Time	`json:"expiresAt"`
+	RevokedAt		*time.Time	`json:"revokedAt"`
+	CreatedAt		time.Time	`json:"createdAt"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	RoleAdmin		= "admin"
+	RoleSeller		= "seller"
+	RoleCustomer	= "customer"
+)
+
+const (
+	PermissionProductsRead	= "products:read"
+	PermissionProductsWrite	= "products:write"
+	PermissionRolesRead		= "roles:read"
+	PermissionRolesAssign	= "roles:assign"
+	PermissionUsersUnlock	= "users:unlock"
+)
+
+// DefaultRole is given to every newly registered user. Sellers keep the
+// previous behaviour where any registered user may create products.
+const DefaultRole = RoleSeller
+
+// RolePermissions is the permission set seeded for the built in roles.
+var RolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermissionProductsRead,
+		PermissionProductsWrite,
+		PermissionRolesRead,
+		PermissionRolesAssign,
+		PermissionUsersUnlock,
+	},
+	RoleSeller: {
+		PermissionProductsRead,
+		PermissionProductsWrite,
+	},
+	RoleCustomer: {
+		PermissionProductsRead,
+	},
+}
+
+type Role struct {
+	ID			uuid.UUID		`gorm:"type:uuid;primaryKey" json:"id"`
+	Name		string			`gorm:"unique" json:"name"`
+
+	Permissions	[]Permission	`gorm:"many2many:role_permissions" json:"permissions"`
+}
+
+type Permission struct {
+	ID			uuid.UUID		`gorm:"type:uuid;primaryKey" json:"id"`
+	Name		string			`gorm:"unique" json:"name"`
+}
+
+func (r *Role) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (p *Permission) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
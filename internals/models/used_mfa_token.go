@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsedMFAToken records the jti of an MFA token that was exchanged for a
+// session, so the token can not be used a second time. The row is only
+// needed until the token would have expired anyway.
+type UsedMFAToken struct {
+	ID			uuid.UUID	`gorm:"type:uuid;primaryKey" json:"id"`
+	ExpiresAt	time.Time	`gorm:"index" json:"expiresAt"`
+}
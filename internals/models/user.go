@@ -4,16 +4,69 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type User struct {
-	ID 			uuid.UUID 	`gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
-	Name		string		`json:"name"`
-	Email		string		`gorm:"unique" json:"email"`
-	Password	string		`json:"password"`
+	ID 				uuid.UUID 	`gorm:"type:uuid;primaryKey" json:"id"`
+	Name			string		`json:"name"`
+	Email			string		`gorm:"unique" json:"email"`
+	Password		string		`json:"password"`
+	// EmailVerifiedAt is nil until the user opens the verification link.
+	EmailVerifiedAt	*time.Time	`json:"emailVerifiedAt"`
+	// MFASecret is the TOTP secret. It is set at enrollment and only active
+	// once MFAEnabledAt is set by the confirmation.
+	MFASecret		string		`json:"-"`
+	MFAEnabledAt	*time.Time	`json:"mfaEnabledAt"`
+	// MFALastCounter is the TOTP period of the last accepted code. Codes of
+	// that period or earlier are refused, so each code works only once.
+	MFALastCounter	uint64		`json:"-"`
 
-	Products	[]Product	`gorm:"foreignKey:UserID" json:"reviews"`
+	Products		[]Product	`gorm:"foreignKey:UserID" json:"reviews"`
+	Roles			[]Role		`gorm:"many2many:user_roles" json:"roles"`
 
-	CreatedAt	time.Time	`json:"createdAt"`
-	UpdatedAt	time.Time	`json:"updatedAt"`
-}
\ No newline at end of file
+	CreatedAt		time.Time	`json:"createdAt"`
+	UpdatedAt		time.Time	`json:"updatedAt"`
+}
+
+// RoleNames returns the names of the roles assigned to the user.
+func (u *User) RoleNames() []string {
+	names := make([]string, 0, len(u.Roles))
+	for _, role := range u.Roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// PermissionNames returns the permissions granted by all roles of the user.
+func (u *User) PermissionNames() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, role := range u.Roles {
+		for _, permission := range role.Permissions {
+			if !seen[permission.Name] {
+				seen[permission.Name] = true
+				names = append(names, permission.Name)
+			}
+		}
+	}
+	return names
+}
+
+// EmailVerified reports whether the user has verified their email address.
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// MFAEnabled reports whether login requires a second factor.
+func (u *User) MFAEnabled() bool {
+	return u.MFASecret != "" && u.MFAEnabledAt != nil
+}
+
+// BeforeCreate assigns the ID in Go so every database driver works the same.
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
+}
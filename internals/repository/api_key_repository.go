@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(context context.Context, key *models.APIKey) error
+	FindByHash(context context.Context, keyHash string) (*models.APIKey, error)
+	// FindByUser lists the keys of the user that are not revoked, newest first.
+	FindByUser(context context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	// Revoke revokes a key of the user. It returns gorm.ErrRecordNotFound when
+	// the user has no such active key.
+	Revoke(context context.Context, id, userID uuid.UUID) error
+	Touch(context context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *apiKeyRepository {
+	return &apiKeyRepository{DB: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	return r.DB.WithContext(ctx).Create(key).Error
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+
+	if err := r.DB.WithContext(ctx).First(&key, "key_hash = ?", keyHash).Error; err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	var keys []models.APIKey
+
+	err := r.DB.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&keys).Error
+
+	return keys, err
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id, userID uuid.UUID) error {
+	result := r.DB.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	return r.DB.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type EmailVerificationRepository interface {
+	Create(context context.Context, token *models.EmailVerificationToken) error
+	FindByHash(context context.Context, tokenHash string) (*models.EmailVerificationToken, error)
+	// MarkUsed returns false when the token was already used.
+	MarkUsed(context context.Context, id uuid.UUID) (bool, error)
+}
+
+type emailVerificationRepository struct {
+	DB *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) *emailVerificationRepository {
+	return &emailVerificationRepository{DB: db}
+}
+
+func (r *emailVerificationRepository) Create(ctx context.Context, token *models.EmailVerificationToken) error {
+	return r.DB.WithContext(ctx).Create(token).Error
+}
+
+func (r *emailVerificationRepository) FindByHash(ctx context.Context, tokenHash string) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+
+	if err := r.DB.WithContext(ctx).First(&token, "token_hash = ?", tokenHash).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *emailVerificationRepository) MarkUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.DB.WithContext(ctx).Model(&models.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+
+	return result.RowsAffected == 1, result.Error
+}
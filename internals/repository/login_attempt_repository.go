@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttemptRepository interface {
+	// Find returns gorm.ErrRecordNotFound when key has no failures.
+	Find(context context.Context, key string) (*models.LoginAttempt, error)
+	// Fail counts one more failure of key and returns the number of failures.
+	// The count starts over when the last failure is older than window.
+	// Keys that are past window and not locked are deleted on the way, so
+	// guessing made up emails does not grow the storage.
+	Fail(context context.Context, key string, window time.Duration) (int, error)
+	Lock(context context.Context, key string, until time.Time) error
+	Reset(context context.Context, key string) error
+}
+
+type loginAttemptRepository struct {
+	DB *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *loginAttemptRepository {
+	return &loginAttemptRepository{DB: db}
+}
+
+func (r *loginAttemptRepository) Find(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	if err := r.DB.WithContext(ctx).First(&attempt, "key = ?", key).Error; err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// Fail increments the counter in a single upsert, so concurrent instances
+// never lose a failure.
+func (r *loginAttemptRepository) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	now := time.Now()
+	attempt := models.LoginAttempt{Key: key, Failures: 1, UpdatedAt: now}
+
+	err := r.DB.WithContext(ctx).
+		Where("updated_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-window), now).
+		Delete(&models.LoginAttempt{}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"failures": gorm.Expr("CASE WHEN login_attempts.updated_at < ? THEN 1 ELSE login_attempts.failures + 1 END", now.Add(-window)),
+			"updated_at": now,
+		}),
+	}).Create(&attempt).Error
+	if err != nil {
+		return 0, err
+	}
+
+	stored, err := r.Find(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	return stored.Failures, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	return r.DB.WithContext(ctx).Model(&models.LoginAttempt{}).
+		Where("key = ?", key).
+		Update("locked_until", until).Error
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	return r.DB.WithContext(ctx).Delete(&models.LoginAttempt{}, "key = ?", key).Error
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type memoryAPIKeyRepository struct {
+	mu		sync.Mutex
+	keys	map[uuid.UUID]models.APIKey
+}
+
+func NewMemoryAPIKeyRepository() *memoryAPIKeyRepository {
+	return &memoryAPIKeyRepository{keys: map[uuid.UUID]models.APIKey{}}
+}
+
+func (r *memoryAPIKeyRepository) Create(context context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.KeyHash == key.KeyHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	stored := *key
+	stored.Scopes = append([]string(nil), key.Scopes...)
+	r.keys[key.ID] = stored
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) FindByHash(context context.Context, keyHash string) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			return &key, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryAPIKeyRepository) FindByUser(context context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var keys []models.APIKey
+	for _, key := range r.keys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(context context.Context, id, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	r.keys[id] = key
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) Touch(context context.Context, id uuid.UUID, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[id]; ok {
+		key.LastUsedAt = &usedAt
+		r.keys[id] = key
+	}
+
+	return nil
+}
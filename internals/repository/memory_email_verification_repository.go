@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type memoryEmailVerificationRepository struct {
+	mu		sync.Mutex
+	tokens	map[uuid.UUID]models.EmailVerificationToken
+}
+
+func NewMemoryEmailVerificationRepository() *memoryEmailVerificationRepository {
+	return &memoryEmailVerificationRepository{tokens: map[uuid.UUID]models.EmailVerificationToken{}}
+}
+
+func (r *memoryEmailVerificationRepository) Create(context context.Context, token *models.EmailVerificationToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *memoryEmailVerificationRepository) FindByHash(context context.Context, tokenHash string) (*models.EmailVerificationToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryEmailVerificationRepository) MarkUsed(context context.Context, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	r.tokens[id] = token
+	return true, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type memoryLoginAttemptRepository struct {
+	mu			sync.Mutex
+	attempts	map[string]models.LoginAttempt
+}
+
+func NewMemoryLoginAttemptRepository() *memoryLoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: map[string]models.LoginAttempt{}}
+}
+
+func (r *memoryLoginAttemptRepository) Find(context context.Context, key string) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) Fail(context context.Context, key string, window time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for stale, attempt := range r.attempts {
+		if attempt.UpdatedAt.Before(now.Add(-window)) && !attempt.Locked(now) {
+			delete(r.attempts, stale)
+		}
+	}
+
+	attempt, ok := r.attempts[key]
+	if !ok || attempt.UpdatedAt.Before(now.Add(-window)) {
+		attempt.Key = key
+		attempt.Failures = 0
+	}
+
+	attempt.Failures++
+	attempt.UpdatedAt = now
+	r.attempts[key] = attempt
+
+	return attempt.Failures, nil
+}
+
+func (r *memoryLoginAttemptRepository) Lock(context context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempt, ok := r.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		r.attempts[key] = attempt
+	}
+
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(context context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type memoryPasswordResetRepository struct {
+	mu		sync.Mutex
+	tokens	map[uuid.UUID]models.PasswordResetToken
+}
+
+func NewMemoryPasswordResetRepository() *memoryPasswordResetRepository {
+	return &memoryPasswordResetRepository{tokens: map[uuid.UUID]models.PasswordResetToken{}}
+}
+
+func (r *memoryPasswordResetRepository) Create(context context.Context, token *models.PasswordResetToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *memoryPasswordResetRepository) FindByHash(context context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryPasswordResetRepository) MarkUsed(context context.Context, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	r.tokens[id] = token
+	return true, nil
+}
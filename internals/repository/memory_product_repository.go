@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type memoryProductRepository struct {
+	mu			sync.RWMutex
+	products	map[uuid.UUID]models.Product
+	users		*memoryUserRepository
+}
+
+func NewMemoryProductRepository(users *memoryUserRepository) *memoryProductRepository {
+	return &memoryProductRepository{
+		products: map[uuid.UUID]models.Product{},
+		users: users,
+	}
+}
+
+func (r *memoryProductRepository) FindAll(context context.Context, filter ProductFilter) ([]models.Product, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search := strings.ToLower(filter.Search)
+
+	var matched []models.Product
+	for _, product := range r.products {
+		if search != "" && !strings.Contains(strings.ToLower(product.Name), search) {
+			continue
+		}
+		if filter.MinPrice != nil && product.Price < *filter.MinPrice {
+			continue
+		}
+		if filter.MaxPrice != nil && product.Price > *filter.MaxPrice {
+			continue
+		}
+		if filter.UserID != nil && product.UserID != *filter.UserID {
+			continue
+		}
+		matched = append(matched, product)
+	}
+
+	total := int64(len(matched))
+
+	compare := productComparator(filter.SortField)
+	sort.Slice(matched, func(i, j int) bool {
+		if filter.Desc {
+			return compare(matched[j], matched[i]) < 0
+		}
+		return compare(matched[i], matched[j]) < 0
+	})
+
+	if filter.After != nil {
+		after := models.Product{
+			ID: filter.After.ID,
+			Name: filter.After.Name,
+			Price: filter.After.Price,
+			CreatedAt: filter.After.CreatedAt,
+		}
+
+		start := len(matched)
+		for i, product := range matched {
+			c := compare(product, after)
+			if (!filter.Desc && c > 0) || (filter.Desc && c < 0) {
+				start = i
+				break
+			}
+		}
+		matched = matched[start:]
+	} else if filter.Offset > 0 {
+		if filter.Offset >= len(matched) {
+			matched = nil
+		} else {
+			matched = matched[filter.Offset:]
+		}
+	}
+
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+
+	return matched, total, nil
+}
+
+// productComparator orders products by the sort field, then by ID, like the
+// ORDER BY used by the GORM repository.
+func productComparator(sortField string) func(a, b models.Product) int {
+	return func(a, b models.Product) int {
+		var c int
+		switch ProductSortFields[sortField] {
+		case "price":
+			c = compareOrdered(a.Price, b.Price)
+		case "name":
+			c = strings.Compare(a.Name, b.Name)
+		default:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+
+		if c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	}
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (r *memoryProductRepository) FindByID(context context.Context, id uuid.UUID) (*models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	product, ok := r.products[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &product, nil
+}
+
+func (r *memoryProductRepository) Create(context context.Context, product *models.Product) error {
+	if !r.users.exists(product.UserID) {
+		return errors.New("violates foreign key constraint: user does not exist")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if product.ID == uuid.Nil {
+		product.ID = uuid.New()
+	}
+	product.CreatedAt = time.Now()
+	product.UpdatedAt = product.CreatedAt
+
+	stored := *product
+	stored.User = models.User{}
+	r.products[product.ID] = stored
+
+	return nil
+}
+
+func (r *memoryProductRepository) Update(context context.Context, product *models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[product.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	stored.Name = product.Name
+	stored.Price = product.Price
+	stored.UpdatedAt = time.Now()
+	r.products[product.ID] = stored
+	product.UpdatedAt = stored.UpdatedAt
+
+	return nil
+}
+
+func (r *memoryProductRepository) Delete(context context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	delete(r.products, id)
+	return nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/ratelimit"
+)
+
+type memoryRateLimitRepository struct {
+	mu			sync.Mutex
+	states		map[string]ratelimit.State
+	lastSweep	time.Time
+}
+
+func NewMemoryRateLimitRepository() *memoryRateLimitRepository {
+	return &memoryRateLimitRepository{states: map[string]ratelimit.State{}}
+}
+
+func (r *memoryRateLimitRepository) Update(context context.Context, key string, fn func(state *ratelimit.State)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastSweep) >= sweepInterval {
+		for k, state := range r.states {
+			if state.ExpiresAt.Before(now) {
+				delete(r.states, k)
+			}
+		}
+		r.lastSweep = now
+	}
+
+	state := r.states[key]
+	fn(&state)
+	r.states[key] = state
+
+	return nil
+}
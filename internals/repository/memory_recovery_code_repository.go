@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+)
+
+type memoryRecoveryCodeRepository struct {
+	mu		sync.Mutex
+	codes	map[uuid.UUID][]models.RecoveryCode
+}
+
+func NewMemoryRecoveryCodeRepository() *memoryRecoveryCodeRepository {
+	return &memoryRecoveryCodeRepository{codes: map[uuid.UUID][]models.RecoveryCode{}}
+}
+
+func (r *memoryRecoveryCodeRepository) Replace(context context.Context, userID uuid.UUID, codes []models.RecoveryCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.codes[userID] = append([]models.RecoveryCode(nil), codes...)
+	return nil
+}
+
+func (r *memoryRecoveryCodeRepository) Use(context context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	codes := r.codes[userID]
+	for i := range codes {
+		if codes[i].CodeHash == codeHash && codes[i].UsedAt == nil {
+			now := time.Now()
+			codes[i].UsedAt = &now
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type memoryRefreshTokenRepository struct {
+	mu		sync.Mutex
+	tokens	map[uuid.UUID]models.RefreshToken
+}
+
+func NewMemoryRefreshTokenRepository() *memoryRefreshTokenRepository {
+	return &memoryRefreshTokenRepository{tokens: map[uuid.UUID]models.RefreshToken{}}
+}
+
+func (r *memoryRefreshTokenRepository) Create(context context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *memoryRefreshTokenRepository) FindByID(context context.Context, id uuid.UUID) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &token, nil
+}
+
+func (r *memoryRefreshTokenRepository) Rotate(context context.Context, oldID uuid.UUID, next *models.RefreshToken) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.tokens[oldID]
+	if !ok || old.RevokedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	old.RevokedAt = &now
+	old.ReplacedByID = &next.ID
+	r.tokens[oldID] = old
+	r.tokens[next.ID] = *next
+
+	return true, nil
+}
+
+func (r *memoryRefreshTokenRepository) RevokeFamily(context context.Context, familyID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.tokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryRefreshTokenRepository) RevokeByUser(context context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.tokens[id] = token
+		}
+	}
+
+	return nil
+}
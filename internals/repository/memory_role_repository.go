@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+)
+
+type memoryRoleRepository struct {
+	mu		sync.RWMutex
+	roles	[]models.Role
+}
+
+// NewMemoryRoleRepository returns the built in roles of models.RolePermissions.
+func NewMemoryRoleRepository() *memoryRoleRepository {
+	permissions := map[string]models.Permission{}
+
+	var roles []models.Role
+	for name, permissionNames := range models.RolePermissions {
+		role := models.Role{ID: uuid.New(), Name: name}
+		for _, permissionName := range permissionNames {
+			permission, ok := permissions[permissionName]
+			if !ok {
+				permission = models.Permission{ID: uuid.New(), Name: permissionName}
+				permissions[permissionName] = permission
+			}
+			role.Permissions = append(role.Permissions, permission)
+		}
+		roles = append(roles, role)
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+
+	return &memoryRoleRepository{roles: roles}
+}
+
+func (r *memoryRoleRepository) FindAll(context context.Context) ([]models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.roles), nil
+}
+
+func (r *memoryRoleRepository) FindByNames(context context.Context, names []string) ([]models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var roles []models.Role
+	for _, role := range r.roles {
+		if slices.Contains(names, role.Name) {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles, nil
+}
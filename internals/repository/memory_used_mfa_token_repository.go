@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type memoryUsedMFATokenRepository struct {
+	mu		sync.Mutex
+	tokens	map[uuid.UUID]time.Time
+}
+
+func NewMemoryUsedMFATokenRepository() *memoryUsedMFATokenRepository {
+	return &memoryUsedMFATokenRepository{tokens: map[uuid.UUID]time.Time{}}
+}
+
+func (r *memoryUsedMFATokenRepository) Use(context context.Context, id uuid.UUID, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for tokenID, expires := range r.tokens {
+		if expires.Before(now) {
+			delete(r.tokens, tokenID)
+		}
+	}
+
+	if _, ok := r.tokens[id]; ok {
+		return false, nil
+	}
+
+	r.tokens[id] = expiresAt
+	return true, nil
+}
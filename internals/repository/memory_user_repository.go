@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type memoryUserRepository struct {
+	mu		sync.RWMutex
+	users	map[uuid.UUID]models.User
+}
+
+func NewMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{users: map[uuid.UUID]models.User{}}
+}
+
+func (r *memoryUserRepository) FindByID(context context.Context, id uuid.UUID) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindByEmail(context context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepository) Create(context context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	user.UpdatedAt = time.Now()
+
+	stored := *user
+	stored.Products = nil
+	stored.Roles = append([]models.Role(nil), user.Roles...)
+	r.users[user.ID] = stored
+
+	return nil
+}
+
+func (r *memoryUserRepository) SetRoles(context context.Context, user *models.User, roles []models.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	stored.Roles = append([]models.Role(nil), roles...)
+	r.users[user.ID] = stored
+	user.Roles = roles
+
+	return nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(context context.Context, id uuid.UUID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	stored.Password = passwordHash
+	stored.UpdatedAt = time.Now()
+	r.users[id] = stored
+
+	return nil
+}
+
+func (r *memoryUserRepository) MarkEmailVerified(context context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	if stored.EmailVerifiedAt == nil {
+		now := time.Now()
+		stored.EmailVerifiedAt = &now
+		r.users[id] = stored
+	}
+
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateMFA(context context.Context, id uuid.UUID, secret string, enabledAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	stored.MFASecret = secret
+	stored.MFAEnabledAt = enabledAt
+	stored.UpdatedAt = time.Now()
+	r.users[id] = stored
+
+	return nil
+}
+
+func (r *memoryUserRepository) UseMFACounter(context context.Context, id uuid.UUID, counter uint64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[id]
+	if !ok || stored.MFALastCounter >= counter {
+		return false, nil
+	}
+
+	stored.MFALastCounter = counter
+	r.users[id] = stored
+
+	return true, nil
+}
+
+// exists reports whether a user with id is stored, used to emulate the
+// products.user_id foreign key.
+func (r *memoryUserRepository) exists(id uuid.UUID) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.users[id]
+	return ok
+}
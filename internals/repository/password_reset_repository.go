@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	Create(context context.Context, token *models.PasswordResetToken) error
+	FindByHash(context context.Context, tokenHash string) (*models.PasswordResetToken, error)
+	// MarkUsed returns false when the token was already used.
+	MarkUsed(context context.Context, id uuid.UUID) (bool, error)
+}
+
+type passwordResetRepository struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) *passwordResetRepository {
+	return &passwordResetRepository{DB: db}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	return r.DB.WithContext(ctx).Create(token).Error
+}
+
+func (r *passwordResetRepository) FindByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+
+	if err := r.DB.WithContext(ctx).First(&token, "token_hash = ?", tokenHash).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *passwordResetRepository) MarkUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.DB.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+
+	return result.RowsAffected == 1, result.Error
+}
@@ -2,16 +2,51 @@ package repository
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
 	"gorm.io/gorm"
 )
 
+// ProductCursor points at the last product of a page. Only the value of the
+// active sort field is compared, ID breaks ties between equal values.
+type ProductCursor struct {
+	ID			uuid.UUID	`json:"id"`
+	Name		string		`json:"name"`
+	Price		float64		`json:"price"`
+	CreatedAt	time.Time	`json:"createdAt"`
+}
+
+type ProductFilter struct {
+	Search		string
+	MinPrice	*float64
+	MaxPrice	*float64
+	UserID		*uuid.UUID
+
+	// SortField is one of the columns in ProductSortFields.
+	SortField	string
+	Desc		bool
+
+	Limit		int
+	Offset		int
+	After		*ProductCursor
+}
+
+// ProductSortFields maps the public sort names to their column.
+var ProductSortFields = map[string]string{
+	"createdAt":	"created_at",
+	"price":		"price",
+	"name":			"name",
+}
+
 type ProductRepository interface {
-	FindAll(context context.Context) ([]models.Product, error)
+	FindAll(context context.Context, filter ProductFilter) ([]models.Product, int64, error)
 	FindByID(context context.Context, id uuid.UUID) (*models.Product, error)
 	Create(context context.Context, product *models.Product) error
+	Update(context context.Context, product *models.Product) error
+	Delete(context context.Context, id uuid.UUID) error
 }
 
 type productRepository struct {
@@ -22,16 +57,75 @@ func NewProductRepository(db *gorm.DB) *productRepository {
 	return &productRepository{DB: db}
 }
 
-func (r *productRepository) FindAll(ctx context.Context) ([]models.Product, error) {
-	var products []models.Product
-	err := r.DB.WithContext(ctx).Find(&products).Error
-	return products, err
+func (r *productRepository) FindAll(ctx context.Context, filter ProductFilter) ([]models.Product, int64, error) {
+	var (
+		products	[]models.Product
+		total		int64
+	)
+
+	query := r.DB.WithContext(ctx).Model(&models.Product{})
+
+	if filter.Search != "" {
+		query = query.Where("LOWER(name) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(filter.Search))+"%")
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+
+	// Session lets the filtered query be reused for both count and find.
+	query = query.Session(&gorm.Session{})
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := ProductSortFields[filter.SortField]
+	if !ok {
+		column = "created_at"
+	}
+
+	direction, cmp := "ASC", ">"
+	if filter.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		var value any
+		switch column {
+		case "price":
+			value = filter.After.Price
+		case "name":
+			value = filter.After.Name
+		default:
+			value = filter.After.CreatedAt
+		}
+
+		query = query.Where(
+			"("+column+" "+cmp+" ?) OR ("+column+" = ? AND id "+cmp+" ?)",
+			value, value, filter.After.ID,
+		)
+	} else if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	err := query.Order(column + " " + direction).Order("id " + direction).Find(&products).Error
+	return products, total, err
 }
 
 func (r *productRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Product, error)  {
 	var product models.Product
 
-	if err := r.DB.WithContext(ctx).Find(&product, id).Error; err != nil {
+	if err := r.DB.WithContext(ctx).First(&product, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 
@@ -40,4 +134,25 @@ func (r *productRepository) FindByID(ctx context.Context, id uuid.UUID) (*models
 
 func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
 	return r.DB.WithContext(ctx).Create(product).Error
-}
\ No newline at end of file
+}
+
+func (r *productRepository) Update(ctx context.Context, product *models.Product) error {
+	return r.DB.WithContext(ctx).Model(product).Select("Name", "Price", "UpdatedAt").Updates(product).Error
+}
+
+func (r *productRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.DB.WithContext(ctx).Delete(&models.Product{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/ratelimit"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sweepInterval is how often the rate limit stores drop expired states.
+const sweepInterval = time.Minute
+
+type RateLimitRepository interface {
+	// Update passes the state of key to fn and saves it. No other caller,
+	// on this instance or another, sees the state in between.
+	Update(context context.Context, key string, fn func(state *ratelimit.State)) error
+}
+
+type rateLimitRepository struct {
+	DB			*gorm.DB
+
+	mu			sync.Mutex
+	lastSweep	time.Time
+}
+
+func NewRateLimitRepository(db *gorm.DB) *rateLimitRepository {
+	return &rateLimitRepository{DB: db}
+}
+
+// Update locks the row of key for the length of a transaction, the row is
+// created first so there is always one to lock.
+func (r *rateLimitRepository) Update(ctx context.Context, key string, fn func(state *ratelimit.State)) error {
+	r.sweep(ctx)
+
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RateLimit{Key: key, ExpiresAt: now}).Error; err != nil {
+			return err
+		}
+
+		var row models.RateLimit
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&row, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		state := ratelimit.State{Value: row.Value, Previous: row.Previous, At: row.At, ExpiresAt: row.ExpiresAt}
+		fn(&state)
+
+		return tx.Model(&models.RateLimit{}).Where("key = ?", key).Updates(map[string]any{
+			"value": state.Value,
+			"previous": state.Previous,
+			"at": state.At,
+			"expires_at": state.ExpiresAt,
+		}).Error
+	})
+}
+
+// sweep deletes expired rows at most once per sweepInterval on this instance.
+// A failure is logged by the GORM logger and the rows are deleted by a later
+// sweep.
+func (r *rateLimitRepository) sweep(ctx context.Context) {
+	now := time.Now()
+
+	r.mu.Lock()
+	due := now.Sub(r.lastSweep) >= sweepInterval
+	if due {
+		r.lastSweep = now
+	}
+	r.mu.Unlock()
+
+	if !due {
+		return
+	}
+
+	r.DB.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RateLimit{})
+}
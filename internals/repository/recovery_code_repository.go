@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	// Replace deletes the codes of the user and stores codes instead.
+	Replace(context context.Context, userID uuid.UUID, codes []models.RecoveryCode) error
+	// Use spends an unused code of the user, it returns false when there is
+	// no such code.
+	Use(context context.Context, userID uuid.UUID, codeHash string) (bool, error)
+}
+
+type recoveryCodeRepository struct {
+	DB *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) *recoveryCodeRepository {
+	return &recoveryCodeRepository{DB: db}
+}
+
+func (r *recoveryCodeRepository) Replace(ctx context.Context, userID uuid.UUID, codes []models.RecoveryCode) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		if len(codes) == 0 {
+			return nil
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *recoveryCodeRepository) Use(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	result := r.DB.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(context context.Context, token *models.RefreshToken) error
+	FindByID(context context.Context, id uuid.UUID) (*models.RefreshToken, error)
+	// Rotate revokes the old token and stores its replacement. It returns
+	// false when the old token was already revoked, which means it is reused.
+	Rotate(context context.Context, oldID uuid.UUID, next *models.RefreshToken) (bool, error)
+	RevokeFamily(context context.Context, familyID uuid.UUID) error
+	// RevokeByUser revokes every active token of the user, ending all of
+	// their sessions.
+	RevokeByUser(context context.Context, userID uuid.UUID) error
+}
+
+type refreshTokenRepository struct {
+	DB *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *refreshTokenRepository {
+	return &refreshTokenRepository{DB: db}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	return r.DB.WithContext(ctx).Create(token).Error
+}
+
+func (r *refreshTokenRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+
+	if err := r.DB.WithContext(ctx).First(&token, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *refreshTokenRepository) Rotate(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) (bool, error) {
+	rotated := false
+
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", oldID).
+			Updates(map[string]any{"revoked_at": time.Now(), "replaced_by_id": next.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		rotated = true
+		return tx.Create(next).Error
+	})
+
+	return rotated, err
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	return r.DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepository) RevokeByUser(ctx context.Context, userID uuid.UUID) error {
+	return r.DB.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
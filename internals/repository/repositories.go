@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Repositories bundles every repository the services need, so the storage
+// backend can be picked in one place.
+type Repositories struct {
+	Users			UserRepository
+	Products		ProductRepository
+	RefreshTokens	RefreshTokenRepository
+	Roles			RoleRepository
+	PasswordResets	PasswordResetRepository
+	Verifications	EmailVerificationRepository
+	RecoveryCodes	RecoveryCodeRepository
+	APIKeys			APIKeyRepository
+	LoginAttempts	LoginAttemptRepository
+	RateLimits		RateLimitRepository
+	UsedMFATokens	UsedMFATokenRepository
+
+	// db is the database behind the Gorm repositories, nil otherwise.
+	db				*gorm.DB
+}
+
+// Transaction runs fn with repositories that share one database
+// transaction, which is committed when fn returns nil. Without a database,
+// as for the memory repositories, fn simply gets r.
+func (r *Repositories) Transaction(ctx context.Context, fn func(repos *Repositories) error) error {
+	if r.db == nil {
+		return fn(r)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewGormRepositories(tx))
+	})
+}
+
+// NewGormRepositories returns the repositories backed by db, which may be
+// Postgres or SQLite.
+func NewGormRepositories(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users: NewUserRepository(db),
+		Products: NewProductRepository(db),
+		RefreshTokens: NewRefreshTokenRepository(db),
+		Roles: NewRoleRepository(db),
+		PasswordResets: NewPasswordResetRepository(db),
+		Verifications: NewEmailVerificationRepository(db),
+		RecoveryCodes: NewRecoveryCodeRepository(db),
+		APIKeys: NewAPIKeyRepository(db),
+		LoginAttempts: NewLoginAttemptRepository(db),
+		RateLimits: NewRateLimitRepository(db),
+		UsedMFATokens: NewUsedMFATokenRepository(db),
+		db: db,
+	}
+}
+
+// NewMemoryRepositories returns thread safe repositories that keep everything
+// in memory, with the built in roles already seeded.
+func NewMemoryRepositories() *Repositories {
+	users := NewMemoryUserRepository()
+
+	return &Repositories{
+		Users: users,
+		Products: NewMemoryProductRepository(users),
+		RefreshTokens: NewMemoryRefreshTokenRepository(),
+		Roles: NewMemoryRoleRepository(),
+		PasswordResets: NewMemoryPasswordResetRepository(),
+		Verifications: NewMemoryEmailVerificationRepository(),
+		RecoveryCodes: NewMemoryRecoveryCodeRepository(),
+		APIKeys: NewMemoryAPIKeyRepository(),
+		LoginAttempts: NewMemoryLoginAttemptRepository(),
+		RateLimits: NewMemoryRateLimitRepository(),
+		UsedMFATokens: NewMemoryUsedMFATokenRepository(),
+	}
+}
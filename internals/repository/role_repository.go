@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+)
+
+type RoleRepository interface {
+	FindAll(context context.Context) ([]models.Role, error)
+	FindByNames(context context.Context, names []string) ([]models.Role, error)
+}
+
+type roleRepository struct {
+	DB *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) *roleRepository {
+	return &roleRepository{DB: db}
+}
+
+func (r *roleRepository) FindAll(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	err := r.DB.WithContext(ctx).Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) FindByNames(ctx context.Context, names []string) ([]models.Role, error) {
+	var roles []models.Role
+	err := r.DB.WithContext(ctx).Preload("Permissions").Where("name IN ?", names).Find(&roles).Error
+	return roles, err
+}
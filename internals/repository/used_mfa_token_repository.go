@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UsedMFATokenRepository interface {
+	// Use records the MFA token id until expiresAt. It returns false when
+	// the token was already used. Expired records are deleted on the way.
+	Use(context context.Context, id uuid.UUID, expiresAt time.Time) (bool, error)
+}
+
+type usedMFATokenRepository struct {
+	DB *gorm.DB
+}
+
+func NewUsedMFATokenRepository(db *gorm.DB) *usedMFATokenRepository {
+	return &usedMFATokenRepository{DB: db}
+}
+
+func (r *usedMFATokenRepository) Use(ctx context.Context, id uuid.UUID, expiresAt time.Time) (bool, error) {
+	db := r.DB.WithContext(ctx)
+
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&models.UsedMFAToken{}).Error; err != nil {
+		return false, err
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.UsedMFAToken{ID: id, ExpiresAt: expiresAt})
+
+	return result.RowsAffected == 1, result.Error
+}
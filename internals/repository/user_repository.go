@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
@@ -12,6 +13,16 @@ type UserRepository interface {
 	FindByEmail(context context.Context, email string) (*models.User, error)
 	FindByID(context context.Context, id uuid.UUID) (*models.User, error)
 	Create(context context.Context, user *models.User) error
+	SetRoles(context context.Context, user *models.User, roles []models.Role) error
+	UpdatePassword(context context.Context, id uuid.UUID, passwordHash string) error
+	MarkEmailVerified(context context.Context, id uuid.UUID) error
+	// UpdateMFA stores the TOTP secret of the user and when it was enabled.
+	// An empty secret turns two factor authentication off.
+	UpdateMFA(context context.Context, id uuid.UUID, secret string, enabledAt *time.Time) error
+	// UseMFACounter records that the TOTP code of period counter was
+	// accepted. It returns false when that period or a later one was
+	// already used.
+	UseMFACounter(context context.Context, id uuid.UUID, counter uint64) (bool, error)
 }
 
 type userRepository struct {
@@ -25,7 +36,7 @@ func NewUserRepository(db *gorm.DB) *userRepository {
 func (r *userRepository) FindByID(context context.Context, id uuid.UUID) (*models.User, error) {
 	var user models.User
 
-	if err := r.DB.WithContext(context).First(&user, "id = ?", id).Error; err != nil {
+	if err := r.DB.WithContext(context).Preload("Roles.Permissions").First(&user, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 
@@ -35,7 +46,7 @@ func (r *userRepository) FindByID(context context.Context, id uuid.UUID) (*model
 func (r *userRepository) FindByEmail(context context.Context, email string) (*models.User, error){
 	var user models.User
 
-	if err := r.DB.WithContext(context).First(&user, "email = ?", email).Error; err != nil {
+	if err := r.DB.WithContext(context).Preload("Roles.Permissions").First(&user, "email = ?", email).Error; err != nil {
 		return nil, err
 	}
 
@@ -44,4 +55,59 @@ func (r *userRepository) FindByEmail(context context.Context, email string) (*mo
 
 func (r *userRepository) Create(context context.Context, user *models.User) error {
 	return r.DB.WithContext(context).Create(user).Error
-}
\ No newline at end of file
+}
+
+func (r *userRepository) SetRoles(context context.Context, user *models.User, roles []models.Role) error {
+	if err := r.DB.WithContext(context).Model(user).Association("Roles").Replace(roles); err != nil {
+		return err
+	}
+
+	user.Roles = roles
+	return nil
+}
+
+func (r *userRepository) UpdatePassword(context context.Context, id uuid.UUID, passwordHash string) error {
+	result := r.DB.WithContext(context).Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"password": passwordHash, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) MarkEmailVerified(context context.Context, id uuid.UUID) error {
+	result := r.DB.WithContext(context).Model(&models.User{}).
+		Where("id = ? AND email_verified_at IS NULL", id).
+		Update("email_verified_at", time.Now())
+
+	return result.Error
+}
+
+func (r *userRepository) UpdateMFA(context context.Context, id uuid.UUID, secret string, enabledAt *time.Time) error {
+	result := r.DB.WithContext(context).Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"mfa_secret": secret, "mfa_enabled_at": enabledAt, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) UseMFACounter(context context.Context, id uuid.UUID, counter uint64) (bool, error) {
+	result := r.DB.WithContext(context).Model(&models.User{}).
+		Where("id = ? AND mfa_last_counter < ?", id, counter).
+		Update("mfa_last_counter", counter)
+
+	return result.RowsAffected > 0, result.Error
+}
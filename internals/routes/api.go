@@ -6,13 +6,40 @@ import (
 )
 
 type RouteConfig struct {
-	ProductHandler *handlers.ProductHandler
-	AuthHandler    *handlers.AuthHandler
+	ProductHandler      *handlers.ProductHandler
+	AuthHandler         *handlers.AuthHandler
+	KeysHandler         *handlers.KeysHandler
+	HealthHandler       *handlers.HealthHandler
+	APIKeyHandler       *handlers.APIKeyHandler
+	// MetricsHandler is nil when metrics are disabled. MetricsAuth guards it.
+	MetricsHandler      *handlers.MetricsHandler
+	MetricsAuth         fiber.Handler
+	// Authenticate guards the protected routes and sets the user_id local.
+	Authenticate        fiber.Handler
+	// AuthenticateSession only accepts access tokens from a login. It guards
+	// account settings that an API key must not change.
+	AuthenticateSession fiber.Handler
+	// IPRateLimit throttles every /api request by client address before
+	// authentication. AuthRateLimit throttles the login, registration and
+	// account recovery endpoints, APIRateLimit every route behind
+	// authentication.
+	IPRateLimit         fiber.Handler
+	AuthRateLimit       fiber.Handler
+	APIRateLimit        fiber.Handler
 }
 
 func RegisterRoutes(app *fiber.App, cfg *RouteConfig)  {
-	api := app.Group("/api")
+	app.Get("/.well-known/jwks.json", cfg.KeysHandler.JWKS)
+	app.Get("/healthz", cfg.HealthHandler.Live)
+	app.Get("/readyz", cfg.HealthHandler.Ready)
 
-	RegisterAuthRoutes(api, cfg.AuthHandler)
-	RegisterProductRoutes(api, cfg.ProductHandler)
-}
\ No newline at end of file
+	if cfg.MetricsHandler != nil {
+		app.Get("/metrics", cfg.MetricsAuth, cfg.MetricsHandler.Scrape)
+	}
+
+	api := app.Group("/api", cfg.IPRateLimit)
+
+	RegisterAuthRoutes(api, cfg.AuthHandler, cfg.Authenticate, cfg.AuthenticateSession, cfg.AuthRateLimit, cfg.APIRateLimit)
+	RegisterAPIKeyRoutes(api, cfg.APIKeyHandler, cfg.AuthenticateSession, cfg.APIRateLimit)
+	RegisterProductRoutes(api, cfg.ProductHandler, cfg.Authenticate, cfg.APIRateLimit)
+}
@@ -4,13 +4,38 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/handlers"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/middlewares"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
 )
 
-func RegisterAuthRoutes(router fiber.Router, h *handlers.AuthHandler) {
+func RegisterAuthRoutes(router fiber.Router, h *handlers.AuthHandler, authenticate, authenticateSession, authLimit, apiLimit fiber.Handler) {
 	auth := router.Group("/auth")
 
-	auth.Post("/login", h.Login)
-	auth.Post("/register", h.Register)
-	auth.Get("/me", middlewares.JWTProtected(), h.Me)
-	auth.Get("/refresh", h.Refresh)
+	auth.Post("/login", authLimit, h.Login)
+	auth.Post("/register", authLimit, h.Register)
+	auth.Get("/me", authenticate, apiLimit, h.Me)
+	// The refresh cookie is sent cross site, so only POST may use it.
+	auth.Post("/refresh", authLimit, h.Refresh)
+	auth.Post("/logout", authLimit, h.Logout)
+	auth.Post("/forgot-password", authLimit, h.ForgotPassword)
+	auth.Post("/reset-password", authLimit, h.ResetPassword)
+	auth.Post("/password", authenticateSession, apiLimit, h.ChangePassword)
+	auth.Get("/verify", authLimit, h.VerifyEmail)
+	auth.Post("/verify/resend", authLimit, h.ResendVerification)
+
+	auth.Post("/mfa/verify", authLimit, h.VerifyMFA)
+	auth.Post("/mfa/enroll", authenticateSession, apiLimit, h.EnrollMFA)
+	auth.Post("/mfa/confirm", authenticateSession, apiLimit, h.ConfirmMFA)
+	auth.Post("/mfa/disable", authenticateSession, apiLimit, h.DisableMFA)
+
+	auth.Get("/roles", authenticate, apiLimit, middlewares.RequirePermission(models.PermissionRolesRead), h.Roles)
+	auth.Put("/users/:id/roles", authenticate, apiLimit, middlewares.RequirePermission(models.PermissionRolesAssign), h.AssignRoles)
+	auth.Post("/users/:id/unlock", authenticate, apiLimit, middlewares.RequirePermission(models.PermissionUsersUnlock), h.Unlock)
+}
+
+func RegisterAPIKeyRoutes(router fiber.Router, h *handlers.APIKeyHandler, authenticateSession, rateLimit fiber.Handler) {
+	keys := router.Group("/auth/api-keys", authenticateSession, rateLimit)
+
+	keys.Get("/", h.ListAPIKeys)
+	keys.Post("/", h.CreateAPIKey)
+	keys.Delete("/:id", h.RevokeAPIKey)
 }
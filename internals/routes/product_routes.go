@@ -4,11 +4,19 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/handlers"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/middlewares"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
 )
 
-func RegisterProductRoutes(router fiber.Router, h *handlers.ProductHandler) {
-	user := router.Group("/products")
+func RegisterProductRoutes(router fiber.Router, h *handlers.ProductHandler, authenticate, rateLimit fiber.Handler) {
+	user := router.Group("/products", authenticate, rateLimit)
 
-	user.Get("/", middlewares.JWTProtected(), h.GetProducts)
-	user.Post("/", middlewares.JWTProtected(), h.CreateProduct)
-}
\ No newline at end of file
+	read := middlewares.RequirePermission(models.PermissionProductsRead)
+	write := middlewares.RequirePermission(models.PermissionProductsWrite)
+
+	user.Get("/", read, h.GetProducts)
+	user.Post("/", write, h.CreateProduct)
+	user.Get("/:id", read, h.GetProduct)
+	user.Put("/:id", write, h.ReplaceProduct)
+	user.Patch("/:id", write, h.PatchProduct)
+	user.Delete("/:id", write, h.DeleteProduct)
+}
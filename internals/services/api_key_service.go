@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/crypto"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAPIKey	= NewError(KindUnauthorized, "invalid_api_key", "invalid, expired or revoked api key")
+	ErrAPIKeyNotFound	= NewError(KindNotFound, "api_key_not_found", "api key not found")
+	ErrInvalidScope		= NewError(KindInvalid, "invalid_scope", "scope is not a permission you have")
+	ErrInvalidExpiry	= NewError(KindInvalid, "invalid_expiry", "expiresAt must be in the future")
+)
+
+const (
+	// apiKeyPrefix starts every key, so leaked keys are easy to search for.
+	apiKeyPrefix		= "sk_"
+	apiKeySize			= 32
+	// apiKeyDisplayLength is how much of the key is kept in clear text.
+	apiKeyDisplayLength	= 11
+	// apiKeyTouchInterval limits how often LastUsedAt is written, so busy
+	// keys do not cost a database write on every request.
+	apiKeyTouchInterval	= time.Minute
+)
+
+// APIKeyIdentity is the user an API key authenticates and the permissions
+// the request may use.
+type APIKeyIdentity struct {
+	KeyID		uuid.UUID
+	UserID		uuid.UUID
+	Permissions	[]string
+}
+
+type APIKeyService interface {
+	// CreateAPIKey returns the stored key and the key itself, which is not
+	// kept and can not be shown again.
+	CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error
+	Authenticate(ctx context.Context, key string) (*APIKeyIdentity, error)
+}
+
+type apiKeyService struct {
+	Repository		repository.APIKeyRepository
+	UserRepository	repository.UserRepository
+	Logger			*slog.Logger
+	Tracer			trace.Tracer
+}
+
+func NewAPIKeyService(repository repository.APIKeyRepository, userRepository repository.UserRepository, logger *slog.Logger, tracer trace.Tracer) *apiKeyService {
+	return &apiKeyService{
+		Repository: repository,
+		UserRepository: userRepository,
+		Logger: logger,
+		Tracer: tracer,
+	}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (_ *models.APIKey, _ string, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "APIKeyService.CreateAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.UserRepository.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrUserNotFound
+		}
+		return nil, "", Internal(err)
+	}
+
+	granted := user.PermissionNames()
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", ErrInvalidExpiry
+	}
+
+	secret, err := crypto.GenerateToken(apiKeySize)
+	if err != nil {
+		return nil, "", Internal(err)
+	}
+	plain := apiKeyPrefix + secret
+
+	key := models.APIKey{
+		ID: uuid.New(),
+		UserID: user.ID,
+		Name: name,
+		Prefix: plain[:apiKeyDisplayLength],
+		KeyHash: crypto.HashToken(plain),
+		Scopes: slices.Compact(slices.Sorted(slices.Values(scopes))),
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.Repository.Create(ctx, &key); err != nil {
+		return nil, "", Internal(err)
+	}
+
+	return &key, plain, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, userID uuid.UUID) (_ []models.APIKey, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "APIKeyService.ListAPIKeys")
+	defer func() { tracing.End(span, err) }()
+
+	keys, err := s.Repository.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, Internal(err)
+	}
+
+	return keys, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "APIKeyService.RevokeAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.Repository.Revoke(ctx, id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAPIKeyNotFound
+		}
+		return Internal(err)
+	}
+
+	return nil
+}
+
+// Authenticate resolves an API key. The key may only use the scopes the
+// user still has, so removing a role also narrows the keys of the user.
+func (s *apiKeyService) Authenticate(ctx context.Context, plain string) (_ *APIKeyIdentity, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "APIKeyService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.Repository.FindByHash(ctx, crypto.HashToken(plain))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, Internal(err)
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, key.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, Internal(err)
+	}
+
+	granted := user.PermissionNames()
+	permissions := []string{}
+	for _, scope := range key.Scopes {
+		if slices.Contains(granted, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	// LastUsedAt is informational, it must not fail the request.
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.Repository.Touch(ctx, key.ID, now); err != nil {
+			s.Logger.WarnContext(ctx, "touch api key", "api_key_id", key.ID.String(), "error", err.Error())
+		}
+	}
+
+	return &APIKeyIdentity{KeyID: key.ID, UserID: user.ID, Permissions: permissions}, nil
+}
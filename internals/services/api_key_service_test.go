@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// seller memberi user role seller
+func seller(user *models.User) *models.User {
+	user.Roles, _ = (&mockRoleRepository{}).FindByNames(context.Background(), []string{models.RoleSeller})
+	return user
+}
+
+// mockAPIKeyRepository menyimpan key di memory, Touch bisa diganti
+type mockAPIKeyRepository struct {
+	repository.APIKeyRepository
+	mockTouch func(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+func (m *mockAPIKeyRepository) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	if m.mockTouch != nil {
+		return m.mockTouch(ctx, id, usedAt)
+	}
+	return m.APIKeyRepository.Touch(ctx, id, usedAt)
+}
+
+func TestCreateAPIKey_Authenticate(t *testing.T) {
+	user := seller(&models.User{ID: uuid.New()})
+	service := NewAPIKeyService(repository.NewMemoryAPIKeyRepository(), singleUserRepository(user), logging.Discard(), tracing.Noop())
+	ctx := context.Background()
+
+	key, plain, err := service.CreateAPIKey(ctx, user.ID, "ci", []string{models.PermissionProductsWrite}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+
+	assert.True(t, len(plain) > apiKeyDisplayLength)
+	assert.Equal(t, plain[:apiKeyDisplayLength], key.Prefix)
+	assert.NotContains(t, key.KeyHash, plain)
+
+	identity, err := service.Authenticate(ctx, plain)
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+	assert.Equal(t, user.ID, identity.UserID)
+	assert.Equal(t, []string{models.PermissionProductsWrite}, identity.Permissions)
+
+	// Role dicabut: scope ikut hilang
+	user.Roles = nil
+	identity, err = service.Authenticate(ctx, plain)
+	assert.NoError(t, err)
+	assert.Empty(t, identity.Permissions)
+
+	assert.NoError(t, service.RevokeAPIKey(ctx, user.ID, key.ID))
+	_, err = service.Authenticate(ctx, plain)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestCreateAPIKey_InvalidScopeAndExpiry(t *testing.T) {
+	user := seller(&models.User{ID: uuid.New()})
+	service := NewAPIKeyService(repository.NewMemoryAPIKeyRepository(), singleUserRepository(user), logging.Discard(), tracing.Noop())
+	ctx := context.Background()
+
+	_, _, err := service.CreateAPIKey(ctx, user.ID, "ci", []string{models.PermissionRolesAssign}, nil)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	past := time.Now().Add(-time.Hour)
+	_, _, err = service.CreateAPIKey(ctx, user.ID, "ci", []string{models.PermissionProductsRead}, &past)
+	assert.ErrorIs(t, err, ErrInvalidExpiry)
+}
+
+func TestAuthenticate_ExpiredAndUnknownKey(t *testing.T) {
+	user := seller(&models.User{ID: uuid.New()})
+	keys := repository.NewMemoryAPIKeyRepository()
+	service := NewAPIKeyService(keys, singleUserRepository(user), logging.Discard(), tracing.Noop())
+	ctx := context.Background()
+
+	plain := apiKeyPrefix + "expired"
+	past := time.Now().Add(-time.Minute)
+	keys.Create(ctx, &models.APIKey{
+		ID: uuid.New(),
+		UserID: user.ID,
+		KeyHash: crypto.HashToken(plain),
+		Scopes: []string{models.PermissionProductsRead},
+		ExpiresAt: &past,
+	})
+
+	_, err := service.Authenticate(ctx, plain)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, err = service.Authenticate(ctx, apiKeyPrefix+"unknown")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, err = service.Authenticate(ctx, "no-prefix")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAuthenticate_TouchIsBestEffortAndThrottled(t *testing.T) {
+	user := seller(&models.User{ID: uuid.New()})
+	var touches int
+	keys := &mockAPIKeyRepository{
+		APIKeyRepository: repository.NewMemoryAPIKeyRepository(),
+		mockTouch: func(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+			touches++
+			return errors.New("database is read only")
+		},
+	}
+	service := NewAPIKeyService(keys, singleUserRepository(user), logging.Discard(), tracing.Noop())
+	ctx := context.Background()
+
+	_, plain, err := service.CreateAPIKey(ctx, user.ID, "ci", []string{models.PermissionProductsRead}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+
+	// Touch gagal, request tetap jalan
+	_, err = service.Authenticate(ctx, plain)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, touches)
+
+	// Setelah tersimpan, LastUsedAt hanya ditulis sekali per interval
+	touches = 0
+	keys.mockTouch = func(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+		touches++
+		return keys.APIKeyRepository.Touch(ctx, id, usedAt)
+	}
+	for i := 0; i < 3; i++ {
+		_, err = service.Authenticate(ctx, plain)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, touches)
+}
@@ -3,75 +3,229 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/mail"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/metrics"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/crypto"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/jwt"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/password"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/totp"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrInvalidRefreshToken		= NewError(KindUnauthorized, "invalid_refresh_token", "invalid or expired refresh token")
+	ErrRefreshTokenReused		= NewError(KindUnauthorized, "refresh_token_reused", "refresh token reuse detected")
+	ErrUserNotFound				= NewError(KindNotFound, "user_not_found", "user not found")
+	ErrRoleNotFound				= NewError(KindInvalid, "role_not_found", "role not found")
+	ErrInvalidCredentials		= NewError(KindUnauthorized, "invalid_credentials", "invalid credentials")
+	ErrEmailTaken				= NewError(KindConflict, "email_taken", "email already used")
+	ErrInvalidResetToken		= NewError(KindInvalid, "invalid_reset_token", "invalid or expired reset token")
+	ErrInvalidVerificationToken	= NewError(KindInvalid, "invalid_verification_token", "invalid or expired verification token")
+	ErrEmailNotVerified			= NewError(KindForbidden, "email_not_verified", "verify your email address first")
+	ErrInvalidMFAToken			= NewError(KindUnauthorized, "invalid_mfa_token", "invalid or expired mfa token")
+	ErrInvalidMFACode			= NewError(KindUnauthorized, "invalid_mfa_code", "invalid two factor code")
+	ErrMFAAlreadyEnabled		= NewError(KindConflict, "mfa_already_enabled", "two factor authentication is already enabled")
+	ErrMFANotEnrolled			= NewError(KindInvalid, "mfa_not_enrolled", "two factor authentication is not enrolled")
+	ErrTooManyAttempts			= NewError(KindTooManyRequests, "too_many_attempts", "too many failed login attempts, try again later")
+)
+
+// oneTimeTokenSize is the number of random bytes in password reset and email
+// verification tokens.
+const oneTimeTokenSize = 32
+
+// recoveryCodeCount is the number of recovery codes issued when two factor
+// authentication is confirmed.
+const recoveryCodeCount = 10
+
+// LoginResult holds the tokens of a successful login. When the user has two
+// factor authentication enabled only MFAToken is set, to be exchanged for the
+// other tokens by VerifyMFA.
+type LoginResult struct {
+	AccessToken		string
+	RefreshToken	string
+	MFAToken		string
+}
+
+// MFAEnrollment is the secret of a new TOTP enrollment and its otpauth URI.
+type MFAEnrollment struct {
+	Secret	string
+	URI		string
+}
+
 type AuthService interface {
-	Login(context context.Context, email, password string) (string, string, error)
+	Login(context context.Context, email, password string) (*LoginResult, error)
 	Register(context context.Context, user *models.User) error
 	Me(context context.Context, id string) (*models.User, error)
-	Refresh(context context.Context, refreshToken string) (string, error)
+	Refresh(context context.Context, refreshToken string) (string, string, error)
+	Logout(context context.Context, refreshToken string) error
+	Roles(context context.Context) ([]models.Role, error)
+	AssignRoles(context context.Context, userID uuid.UUID, roles []string) (*models.User, error)
+	ForgotPassword(context context.Context, email string) error
+	ResetPassword(context context.Context, token, password string) error
+	ChangePassword(context context.Context, userID uuid.UUID, currentPassword, password string) error
+	VerifyEmail(context context.Context, token string) error
+	ResendVerification(context context.Context, email string) error
+	VerifyMFA(context context.Context, mfaToken, code string) (*LoginResult, error)
+	EnrollMFA(context context.Context, userID uuid.UUID) (*MFAEnrollment, error)
+	ConfirmMFA(context context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableMFA(context context.Context, userID uuid.UUID, code string) error
+	Unlock(context context.Context, userID uuid.UUID) error
 }
 
 type authService struct {
-	Repository repository.UserRepository
+	Repository 				repository.UserRepository
+	TokenRepository			repository.RefreshTokenRepository
+	RoleRepository			repository.RoleRepository
+	ResetRepository			repository.PasswordResetRepository
+	VerificationRepository	repository.EmailVerificationRepository
+	RecoveryCodeRepository	repository.RecoveryCodeRepository
+	UsedMFATokenRepository	repository.UsedMFATokenRepository
+	// Transaction runs writes that span several repositories atomically.
+	Transaction				func(ctx context.Context, fn func(repos *repository.Repositories) error) error
+	Lockout					*lockout
+	Hasher					crypto.Hasher
+	Policy					*password.Policy
+	Tokens					*jwt.Manager
+	Mailer					mail.Sender
+	Config					*config.Config
+	Logger					*slog.Logger
+	// Metrics may be nil.
+	Metrics					*metrics.Metrics
+	Tracer					trace.Tracer
+
+	// dummyHash is checked when the email is unknown, so the response time
+	// does not tell which accounts exist.
+	dummyHash				func() string
 }
 
-func NewAuthService(repository repository.UserRepository) *authService {
-	return &authService{Repository: repository}
+func NewAuthService(repos *repository.Repositories, tokens *jwt.Manager, mailer mail.Sender, policy *password.Policy, cfg *config.Config, logger *slog.Logger, m *metrics.Metrics, tracer trace.Tracer) *authService {
+	hasher := crypto.NewHasher(cfg.Password.Algorithm, cfg.BcryptCost, crypto.Argon2idHasher{
+		Memory: uint32(cfg.Password.Argon2Memory),
+		Iterations: uint32(cfg.Password.Argon2Iterations),
+		Parallelism: uint8(cfg.Password.Argon2Parallelism),
+	})
+
+	return &authService{
+		Repository: repos.Users,
+		TokenRepository: repos.RefreshTokens,
+		RoleRepository: repos.Roles,
+		ResetRepository: repos.PasswordResets,
+		VerificationRepository: repos.Verifications,
+		RecoveryCodeRepository: repos.RecoveryCodes,
+		UsedMFATokenRepository: repos.UsedMFATokens,
+		Transaction: repos.Transaction,
+		Lockout: &lockout{Repository: repos.LoginAttempts, Config: cfg.Lockout},
+		Hasher: hasher,
+		Policy: policy,
+		Tokens: tokens,
+		Mailer: mailer,
+		Config: cfg,
+		Logger: logger,
+		Metrics: m,
+		Tracer: tracer,
+		dummyHash: sync.OnceValue(func() string {
+			hash, _ := hasher.Hash("not the password of any account")
+			return hash
+		}),
+	}
+}
+
+// Login answers ErrInvalidCredentials for unknown emails and wrong passwords
+// alike. Failures are counted per account and per client IP, see lockout.
+// Hashes made with an outdated algorithm or parameters are replaced once the
+// password is known to be right. Every attempt is counted by its outcome in
+// Metrics.
+func (s *authService) Login(ctx context.Context, email, password string) (_ *LoginResult, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.Login")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.login(ctx, email, password)
+	s.Metrics.Login(metrics.StepPassword, loginOutcome(result, err))
+	return result, err
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (string, string, error){
+func (s *authService) login(ctx context.Context, email, password string) (*LoginResult, error) {
+	if err := s.Lockout.check(ctx, email); err != nil {
+		return nil, err
+	}
+
 	user, err := s.Repository.FindByEmail(ctx, email)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", "", errors.New("user not found")
-		}
-		return "", "", err
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, Internal(err)
 	}
 
-	if isMatch := crypto.CheckPasswordHash(password, user.Password); !isMatch {
-		return "", "", errors.New("invalid credentials")
+	if user == nil {
+		s.Hasher.Verify(password, s.dummyHash())
 	}
 
-	accessToken, err := jwt.GenerateAccessToken(user.ID.String())
+	if user == nil || !s.Hasher.Verify(password, user.Password) {
+		if err := s.Lockout.fail(ctx, email); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
 
-	if err != nil {
-		return "", "", errors.New("failed create access token")
+	if err := s.Lockout.reset(ctx, email); err != nil {
+		return nil, err
 	}
-	
-	refreshToken, err := jwt.GenerateRefreshToken(user.ID.String())
 
-	if err != nil {
-		return "", "", errors.New("failed create refresh token")
+	if s.Hasher.NeedsRehash(user.Password) {
+		s.rehash(ctx, user, password)
 	}
-	
-	return accessToken, refreshToken, nil
+
+	if user.MFAEnabled() {
+		mfaToken, err := s.Tokens.GenerateMFAToken(user.ID.String())
+		if err != nil {
+			return nil, Internal(err)
+		}
+		return &LoginResult{MFAToken: mfaToken}, nil
+	}
+
+	return s.issueTokens(ctx, user)
 }
 
-func (s *authService) Register(ctx context.Context, input *models.User) error {
+func (s *authService) Register(ctx context.Context, input *models.User) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.Register")
+	defer func() { tracing.End(span, err) }()
+
 
 	existedUser, err := s.Repository.FindByEmail(ctx, input.Email)
 
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return err
+		return Internal(err)
 	}
 
 	if existedUser != nil {
-		return errors.New("email already used")
+		return ErrEmailTaken
+	}
+
+	if err := s.checkPassword(input.Password, input); err != nil {
+		return err
 	}
 
-	hashedPassword, err :=  crypto.HashPassword(input.Password)
+	hashedPassword, err :=  s.Hasher.Hash(input.Password)
 
 	if err != nil {
-		return errors.New("failed to hashed password")
+		return Internal(err)
+	}
+
+	roles, err := s.RoleRepository.FindByNames(ctx, []string{models.DefaultRole})
+
+	if err != nil {
+		return Internal(err)
 	}
 
 	user := models.User{
@@ -79,47 +233,847 @@ func (s *authService) Register(ctx context.Context, input *models.User) error {
 		Name: input.Name,
 		Email: input.Email,
 		Password: hashedPassword,
+		Roles: roles,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
 	if err := s.Repository.Create(ctx, &user); err != nil {
-		return errors.New("failed to create user")
+		return Internal(err)
+	}
+
+	// The account exists at this point, a failed email can be sent again
+	// through ResendVerification.
+	if err := s.sendVerification(ctx, &user); err != nil {
+		s.Logger.ErrorContext(ctx, "send verification email", "user_id", user.ID.String(), "error", err.Error())
 	}
 
 	return nil
 }
 
-func (s *authService) Me(ctx context.Context, id string) (*models.User, error) {
+func (s *authService) Me(ctx context.Context, id string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.Me")
+	defer func() { tracing.End(span, err) }()
+
 	idVal, err := uuid.Parse(id)
 
 	if err != nil {
-		return nil, errors.New("invalid user id")
+		return nil, ErrInvalidUserID
 	}
 
 	user, err := s.Repository.FindByID(ctx, idVal)
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *authService) Roles(ctx context.Context) (_ []models.Role, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.Roles")
+	defer func() { tracing.End(span, err) }()
+
+	roles, err := s.RoleRepository.FindAll(ctx)
+	if err != nil {
+		return nil, Internal(err)
+	}
+
+	return roles, nil
+}
+
+// AssignRoles replaces the roles of a user. The new roles are picked up by
+// the access token issued on the next login or refresh.
+func (s *authService) AssignRoles(ctx context.Context, userID uuid.UUID, roleNames []string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.AssignRoles")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.Repository.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, Internal(err)
+	}
+
+	roles, err := s.RoleRepository.FindByNames(ctx, roleNames)
+	if err != nil {
+		return nil, Internal(err)
+	}
+
+	if len(roles) != len(slices.Compact(slices.Sorted(slices.Values(roleNames)))) {
+		return nil, ErrRoleNotFound
+	}
+
+	if err := s.Repository.SetRoles(ctx, user, roles); err != nil {
+		return nil, Internal(err)
+	}
+
+	return user, nil
+}
+
+// Refresh rotates the refresh token: the presented token is revoked and a new
+// one from the same family is returned with a new access token. Presenting a
+// token that was already rotated revokes the whole family.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (_ string, _ string, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.Refresh")
+	defer func() { tracing.End(span, err) }()
+
+	record, err := s.findRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	if record.RevokedAt != nil {
+		if err := s.TokenRepository.RevokeFamily(ctx, record.FamilyID); err != nil {
+			return "", "", Internal(err)
+		}
+		return "", "", ErrRefreshTokenReused
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	// Reload the user so role changes are reflected in the new access token.
+	user, err := s.Repository.FindByID(ctx, record.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", "", ErrInvalidRefreshToken
+		}
+		return "", "", Internal(err)
+	}
+
+	accessToken, err := s.Tokens.GenerateAccessToken(user.ID.String(), user.RoleNames(), user.PermissionNames())
+	if err != nil {
+		return "", "", Internal(err)
+	}
+
+	newRefreshToken, err := s.issueRefreshToken(ctx, record.UserID, record.FamilyID, &record.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, newRefreshToken, nil
+}
+
+// Logout revokes every token in the family of the presented refresh token.
+func (s *authService) Logout(ctx context.Context, refreshToken string) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.Logout")
+	defer func() { tracing.End(span, err) }()
+
+	record, err := s.findRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	if err := s.TokenRepository.RevokeFamily(ctx, record.FamilyID); err != nil {
+		return Internal(err)
+	}
+
+	return nil
+}
+
+func (s *authService) findRefreshToken(ctx context.Context, refreshToken string) (*models.RefreshToken, error) {
+	userID, tokenID, err := s.Tokens.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken.Wrap(err)
+	}
+
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	record, err := s.TokenRepository.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, Internal(err)
+	}
+
+	if record.UserID.String() != userID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return record, nil
+}
+
+// issueRefreshToken stores a new refresh token record in familyID and signs
+// it. When previousID is set the previous token is rotated in the same step.
+func (s *authService) issueRefreshToken(ctx context.Context, userID, familyID uuid.UUID, previousID *uuid.UUID) (string, error) {
+	record := models.RefreshToken{
+		ID: uuid.New(),
+		UserID: userID,
+		FamilyID: familyID,
+		ExpiresAt: time.Now().Add(s.Tokens.RefreshTTL()),
+		CreatedAt: time.Now(),
+	}
+
+	if previousID == nil {
+		if err := s.TokenRepository.Create(ctx, &record); err != nil {
+			return "", Internal(err)
+		}
+	} else {
+		rotated, err := s.TokenRepository.Rotate(ctx, *previousID, &record)
+		if err != nil {
+			return "", Internal(err)
+		}
+
+		if !rotated {
+			// Another request rotated this token first, treat it as reuse.
+			if err := s.TokenRepository.RevokeFamily(ctx, familyID); err != nil {
+				return "", Internal(err)
+			}
+			return "", ErrRefreshTokenReused
+		}
+	}
+
+	token, err := s.Tokens.GenerateRefreshToken(userID.String(), record.ID.String())
+	if err != nil {
+		return "", Internal(err)
+	}
+
+	return token, nil
+}
+
+// ForgotPassword mails a single use reset link to the user. Unknown emails
+// are ignored without an error, so the endpoint does not reveal which
+// addresses are registered.
+func (s *authService) ForgotPassword(ctx context.Context, email string) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.ForgotPassword")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.Repository.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return Internal(err)
+	}
+
+	// Only registered emails get this far, so a failure must look the same
+	// as an unknown email to the client.
+	if err := s.sendPasswordReset(ctx, user); err != nil {
+		s.Logger.ErrorContext(ctx, "send password reset email", "user_id", user.ID.String(), "error", err.Error())
+	}
+
+	return nil
+}
+
+func (s *authService) sendPasswordReset(ctx context.Context, user *models.User) error {
+	token, tokenHash, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	record := models.PasswordResetToken{
+		ID: uuid.New(),
+		UserID: user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.Config.PasswordResetTTL),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.ResetRepository.Create(ctx, &record); err != nil {
+		return Internal(err)
+	}
+
+	link := strings.TrimRight(s.Config.ClientURL, "/") + "/reset-password?token=" + token
+	message := mail.Message{
+		From: s.Config.Mail.From,
+		To: user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to choose a new password. It expires in %s.\n\n%s\n\nIf you did not ask for a reset, you can ignore this email.\n",
+			user.Name, s.Config.PasswordResetTTL, link),
+	}
+
+	if err := s.Mailer.Send(ctx, message); err != nil {
+		return Internal(err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. The
+// token is spent before the password changes, and every refresh token of the
+// user is revoked so other sessions have to log in again.
+func (s *authService) ResetPassword(ctx context.Context, token, password string) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+
+	record, err := s.ResetRepository.FindByHash(ctx, crypto.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return Internal(err)
+	}
+
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.Repository.FindByID(ctx, record.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return Internal(err)
+	}
+
+	// A rejected password leaves the token unused for the next attempt.
+	if err := s.checkPassword(password, user); err != nil {
+		return err
+	}
+
+	used, err := s.ResetRepository.MarkUsed(ctx, record.ID)
+	if err != nil {
+		return Internal(err)
+	}
+
+	if !used {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := s.Hasher.Hash(password)
+	if err != nil {
+		return Internal(err)
+	}
+
+	if err := s.Repository.UpdatePassword(ctx, record.UserID, hashedPassword); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return Internal(err)
+	}
+
+	if err := s.TokenRepository.RevokeByUser(ctx, record.UserID); err != nil {
+		return Internal(err)
+	}
+
+	return nil
+}
+
+// ChangePassword replaces the password of a logged in user who knows the
+// current one. Every session of the user is logged out.
+func (s *authService) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, password string) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.ChangePassword")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// A stolen access token must not allow guessing the password, so wrong
+	// guesses count towards the same lockout as Login.
+	if err := s.Lockout.check(ctx, user.Email); err != nil {
+		return err
+	}
+
+	if !s.Hasher.Verify(currentPassword, user.Password) {
+		if err := s.Lockout.fail(ctx, user.Email); err != nil {
+			return err
+		}
+		return Validation(FieldError{Field: "currentPassword", Rule: "incorrect", Message: "is incorrect"})
+	}
+
+	if err := s.Lockout.reset(ctx, user.Email); err != nil {
+		return err
+	}
+
+	if err := s.checkPassword(password, user); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.Hasher.Hash(password)
+	if err != nil {
+		return Internal(err)
+	}
+
+	if err := s.Repository.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return Internal(err)
+	}
+
+	if err := s.TokenRepository.RevokeByUser(ctx, user.ID); err != nil {
+		return Internal(err)
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the address of the user as verified with a token from the
+// verification email.
+func (s *authService) VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.VerifyEmail")
+	defer func() { tracing.End(span, err) }()
+
+	record, err := s.VerificationRepository.FindByHash(ctx, crypto.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return Internal(err)
+	}
+
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return ErrInvalidVerificationToken
+	}
+
+	used, err := s.VerificationRepository.MarkUsed(ctx, record.ID)
+	if err != nil {
+		return Internal(err)
+	}
+
+	if !used {
+		return ErrInvalidVerificationToken
+	}
+
+	if err := s.Repository.MarkEmailVerified(ctx, record.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
 		}
+		return Internal(err)
+	}
+
+	return nil
+}
+
+// ResendVerification mails a new verification link. Like ForgotPassword it
+// does not reveal whether the email is registered or already verified.
+func (s *authService) ResendVerification(ctx context.Context, email string) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.ResendVerification")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.Repository.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return Internal(err)
+	}
+
+	if user.EmailVerified() {
+		return nil
+	}
+
+	// As in ForgotPassword, failures must not tell registered emails apart.
+	if err := s.sendVerification(ctx, user); err != nil {
+		s.Logger.ErrorContext(ctx, "send verification email", "user_id", user.ID.String(), "error", err.Error())
+	}
+
+	return nil
+}
+
+func (s *authService) sendVerification(ctx context.Context, user *models.User) error {
+	token, tokenHash, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	record := models.EmailVerificationToken{
+		ID: uuid.New(),
+		UserID: user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.Config.EmailVerificationTTL),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.VerificationRepository.Create(ctx, &record); err != nil {
+		return Internal(err)
+	}
+
+	link := strings.TrimRight(s.Config.PublicURL, "/") + "/api/auth/verify?token=" + token
+	message := mail.Message{
+		From: s.Config.Mail.From,
+		To: user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to verify your email address. It expires in %s.\n\n%s\n",
+			user.Name, s.Config.EmailVerificationTTL, link),
+	}
+
+	if err := s.Mailer.Send(ctx, message); err != nil {
+		return Internal(err)
+	}
 
+	return nil
+}
+
+// newOneTimeToken returns a random token for an emailed link together with
+// the hash that is stored in its place.
+func newOneTimeToken() (string, string, error) {
+	token, err := crypto.GenerateToken(oneTimeTokenSize)
+	if err != nil {
+		return "", "", Internal(err)
+	}
+
+	return token, crypto.HashToken(token), nil
+}
+
+// VerifyMFA finishes a login of a user with two factor authentication. code
+// is either the current TOTP code or an unused recovery code.
+func (s *authService) VerifyMFA(ctx context.Context, mfaToken, code string) (_ *LoginResult, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.VerifyMFA")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.verifyMFA(ctx, mfaToken, code)
+	s.Metrics.Login(metrics.StepMFA, loginOutcome(result, err))
+	return result, err
+}
+
+func (s *authService) verifyMFA(ctx context.Context, mfaToken, code string) (*LoginResult, error) {
+	userID, tokenID, err := s.Tokens.ValidateMFAToken(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken.Wrap(err)
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	jti, err := uuid.Parse(tokenID)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.Repository.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, Internal(err)
+	}
+
+	if !user.MFAEnabled() {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := s.Lockout.check(ctx, user.Email); err != nil {
 		return nil, err
 	}
 
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.Lockout.fail(ctx, user.Email); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	// The token is spent by the first success. It can not outlive its TTL
+	// plus the leeway, so the record is kept that long.
+	used, err := s.UsedMFATokenRepository.Use(ctx, jti, time.Now().Add(s.Config.JWT.MFATTL+s.Config.JWT.Leeway))
+	if err != nil {
+		return nil, Internal(err)
+	}
+	if !used {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := s.Lockout.reset(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// EnrollMFA creates a new TOTP secret for the user. It is not enforced until
+// ConfirmMFA proves the authenticator app produces matching codes.
+func (s *authService) EnrollMFA(ctx context.Context, userID uuid.UUID) (_ *MFAEnrollment, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.EnrollMFA")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, Internal(err)
+	}
+
+	if err := s.Repository.UpdateMFA(ctx, user.ID, secret, nil); err != nil {
+		return nil, Internal(err)
+	}
+
+	return &MFAEnrollment{
+		Secret: secret,
+		URI: totp.URI(s.Config.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA enables two factor authentication with the first code of the
+// authenticator app and returns the recovery codes. They are shown once,
+// only their hashes are stored.
+func (s *authService) ConfirmMFA(ctx context.Context, userID uuid.UUID, code string) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.ConfirmMFA")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if user.MFASecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	// Wrong codes count towards the login lockout, as in VerifyMFA.
+	if err := s.Lockout.check(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	counter, ok := totp.Validate(user.MFASecret, code, time.Now(), user.MFALastCounter)
+	if !ok {
+		if err := s.Lockout.fail(ctx, user.Email); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.Lockout.reset(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recovery codes without MFA, or MFA without recovery codes, would
+	// leave the account half configured.
+	err = s.Transaction(ctx, func(repos *repository.Repositories) error {
+		used, err := repos.Users.UseMFACounter(ctx, user.ID, counter)
+		if err != nil {
+			return Internal(err)
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+
+		if err := repos.RecoveryCodes.Replace(ctx, user.ID, records); err != nil {
+			return Internal(err)
+		}
+
+		now := time.Now()
+		if err := repos.Users.UpdateMFA(ctx, user.ID, user.MFASecret, &now); err != nil {
+			return Internal(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableMFA turns two factor authentication off after checking a code.
+func (s *authService) DisableMFA(ctx context.Context, userID uuid.UUID, code string) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.DisableMFA")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.MFAEnabled() {
+		return ErrMFANotEnrolled
+	}
+
+	// A stolen session must not be able to guess the code and turn MFA off.
+	if err := s.Lockout.check(ctx, user.Email); err != nil {
+		return err
+	}
+
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.Lockout.fail(ctx, user.Email); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	if err := s.Lockout.reset(ctx, user.Email); err != nil {
+		return err
+	}
+
+	return s.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Users.UpdateMFA(ctx, user.ID, "", nil); err != nil {
+			return Internal(err)
+		}
+
+		if err := repos.RecoveryCodes.Replace(ctx, user.ID, nil); err != nil {
+			return Internal(err)
+		}
+
+		return nil
+	})
+}
+
+// Unlock clears the failed logins of the user's account, letting them log in
+// again before the lockout ends. Failures counted per client IP are kept.
+func (s *authService) Unlock(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "AuthService.Unlock")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.Lockout.reset(ctx, user.Email)
+}
+
+// checkPassword applies the password policy to a new password of user,
+// reporting every broken rule as an error of the password field.
+func (s *authService) checkPassword(password string, user *models.User) error {
+	violations := s.Policy.Check(password, user.Name, user.Email)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fields := make([]FieldError, 0, len(violations))
+	for _, violation := range violations {
+		fields = append(fields, FieldError{Field: "password", Rule: violation.Rule, Message: violation.Message})
+	}
+
+	return Validation(fields...)
+}
+
+// loginOutcome is the metrics result of a login step.
+func loginOutcome(result *LoginResult, err error) string {
+	switch {
+	case err == nil && result.MFAToken != "":
+		return metrics.LoginMFARequired
+	case err == nil:
+		return metrics.LoginSuccess
+	case errors.Is(err, ErrTooManyAttempts):
+		return metrics.LoginLocked
+	case AsError(err).Kind == KindInternal:
+		return metrics.LoginError
+	default:
+		return metrics.LoginFailure
+	}
+}
+
+// rehash stores a hash of password made with the current algorithm. A
+// failure only means the upgrade is tried again at the next login.
+func (s *authService) rehash(ctx context.Context, user *models.User, password string) {
+	hashedPassword, err := s.Hasher.Hash(password)
+	if err == nil {
+		err = s.Repository.UpdatePassword(ctx, user.ID, hashedPassword)
+	}
+
+	if err != nil {
+		s.Logger.WarnContext(ctx, "rehash password", "user_id", user.ID.String(), "error", err.Error())
+		return
+	}
+
+	user.Password = hashedPassword
+}
+
+func (s *authService) findUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.Repository.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, Internal(err)
+	}
+
 	return user, nil
 }
 
-func (s *authService) Refresh(ctx context.Context, refreshToken string) (string, error) {
-	userID, err := jwt.ValidateRefreshToken(refreshToken)
+// checkSecondFactor accepts a current TOTP code of the user that was not
+// used before, or spends one of their recovery codes.
+func (s *authService) checkSecondFactor(ctx context.Context, user *models.User, code string) error {
+	if counter, ok := totp.Validate(user.MFASecret, code, time.Now(), user.MFALastCounter); ok {
+		// A concurrent request may have used the same period meanwhile.
+		used, err := s.Repository.UseMFACounter(ctx, user.ID, counter)
+		if err != nil {
+			return Internal(err)
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.RecoveryCodeRepository.Use(ctx, user.ID, crypto.HashToken(normalizeRecoveryCode(code)))
 	if err != nil {
-		return "", err
+		return Internal(err)
+	}
+
+	if !used {
+		return ErrInvalidMFACode
 	}
 
-	accessToken, err := jwt.GenerateAccessToken(userID)
+	return nil
+}
+
+// issueTokens returns the access token and a refresh token of a new family.
+func (s *authService) issueTokens(ctx context.Context, user *models.User) (*LoginResult, error) {
+	accessToken, err := s.Tokens.GenerateAccessToken(user.ID.String(), user.RoleNames(), user.PermissionNames())
 	if err != nil {
-		return "", err
+		return nil, Internal(err)
 	}
 
-	return accessToken, nil
-}
\ No newline at end of file
+	refreshToken, err := s.issueRefreshToken(ctx, user.ID, uuid.New(), nil)
+	if err != nil {
+		return nil, AsError(err)
+	}
+
+	return &LoginResult{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// newRecoveryCodes returns recovery codes formatted as XXXXX-XXXXX and the
+// records storing their hashes.
+func newRecoveryCodes(userID uuid.UUID) ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, nil, Internal(err)
+		}
+
+		code := secret[:5] + "-" + secret[5:10]
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			ID: uuid.New(),
+			UserID: userID,
+			CodeHash: crypto.HashToken(normalizeRecoveryCode(code)),
+			CreatedAt: time.Now(),
+		})
+	}
+
+	return codes, records, nil
+}
+
+// normalizeRecoveryCode lets users type recovery codes without the dash and
+// in lower case.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
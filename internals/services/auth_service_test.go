@@ -1,21 +1,95 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/mail"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/crypto"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/jwt"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/password"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/totp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 )
 
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.BcryptCost = crypto.DefaultCost
+	cfg.JWT.Secret = "test-access-secret-with-32-characters"
+	cfg.JWT.RefreshSecret = "test-refresh-secret-with-32-characters"
+	return cfg
+}
+
+func testTokens() *jwt.Manager {
+	tokens, err := jwt.NewManager(testConfig().JWT)
+	if err != nil {
+		panic(err)
+	}
+	return tokens
+}
+
+func newTestAuthService(userRepo repository.UserRepository, tokenRepo repository.RefreshTokenRepository) *authService {
+	repos := &repository.Repositories{
+		Users: userRepo,
+		RefreshTokens: tokenRepo,
+		Roles: &mockRoleRepository{},
+		PasswordResets: repository.NewMemoryPasswordResetRepository(),
+		Verifications: repository.NewMemoryEmailVerificationRepository(),
+		RecoveryCodes: repository.NewMemoryRecoveryCodeRepository(),
+		LoginAttempts: repository.NewMemoryLoginAttemptRepository(),
+		UsedMFATokens: repository.NewMemoryUsedMFATokenRepository(),
+	}
+	policy, err := password.NewPolicy(testConfig().Password)
+	if err != nil {
+		panic(err)
+	}
+	return NewAuthService(repos, testTokens(), &mockMailer{}, policy, testConfig(), logging.Discard(), nil, tracing.Noop())
+}
+
+// loginTokens membuka LoginResult menjadi access dan refresh token
+func loginTokens(result *LoginResult, err error) (string, string, error) {
+	if err != nil {
+		return "", "", err
+	}
+	return result.AccessToken, result.RefreshToken, nil
+}
+
+// mockMailer menyimpan email yang dikirim, atau gagal dengan err
+type mockMailer struct {
+	messages	[]mail.Message
+	err			error
+}
+
+func (m *mockMailer) Send(ctx context.Context, msg mail.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
 type mockAuthRepository struct {
 	mockRegister    func(ctx context.Context, user *models.User) error
 	mockFindByEmail func(ctx context.Context, email string) (*models.User, error)
-	mockFindByID	func(ctx context.Context, id uuid.UUID) (*models.User, error)
+	mockFindByID			func(ctx context.Context, id uuid.UUID) (*models.User, error)
+	mockUpdatePassword		func(ctx context.Context, id uuid.UUID, passwordHash string) error
+	mockMarkEmailVerified	func(ctx context.Context, id uuid.UUID) error
+	mockUpdateMFA			func(ctx context.Context, id uuid.UUID, secret string, enabledAt *time.Time) error
+	mockUseMFACounter		func(ctx context.Context, id uuid.UUID, counter uint64) (bool, error)
 }
 
 func (m *mockAuthRepository) Create(ctx context.Context, user *models.User) error {
@@ -23,7 +97,43 @@ func (m *mockAuthRepository) Create(ctx context.Context, user *models.User) erro
 }
 
 func (m *mockAuthRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
-	return m.mockFindByID(ctx, id)
+	if m.mockFindByID != nil {
+		return m.mockFindByID(ctx, id)
+	}
+	return &models.User{ID: id}, nil
+}
+
+func (m *mockAuthRepository) SetRoles(ctx context.Context, user *models.User, roles []models.Role) error {
+	user.Roles = roles
+	return nil
+}
+
+func (m *mockAuthRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	if m.mockUpdatePassword != nil {
+		return m.mockUpdatePassword(ctx, id, passwordHash)
+	}
+	return nil
+}
+
+func (m *mockAuthRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	if m.mockMarkEmailVerified != nil {
+		return m.mockMarkEmailVerified(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockAuthRepository) UpdateMFA(ctx context.Context, id uuid.UUID, secret string, enabledAt *time.Time) error {
+	if m.mockUpdateMFA != nil {
+		return m.mockUpdateMFA(ctx, id, secret, enabledAt)
+	}
+	return nil
+}
+
+func (m *mockAuthRepository) UseMFACounter(ctx context.Context, id uuid.UUID, counter uint64) (bool, error) {
+	if m.mockUseMFACounter != nil {
+		return m.mockUseMFACounter(ctx, id, counter)
+	}
+	return true, nil
 }
 
 func (m *mockAuthRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
@@ -33,6 +143,83 @@ func (m *mockAuthRepository) FindByEmail(ctx context.Context, email string) (*mo
 	return nil, nil
 }
 
+type mockRoleRepository struct{}
+
+func (m *mockRoleRepository) FindAll(ctx context.Context) ([]models.Role, error) {
+	return m.FindByNames(ctx, []string{models.RoleAdmin, models.RoleSeller, models.RoleCustomer})
+}
+
+func (m *mockRoleRepository) FindByNames(ctx context.Context, names []string) ([]models.Role, error) {
+	var roles []models.Role
+	for _, name := range names {
+		permissionNames, ok := models.RolePermissions[name]
+		if !ok {
+			continue
+		}
+		role := models.Role{ID: uuid.New(), Name: name}
+		for _, permission := range permissionNames {
+			role.Permissions = append(role.Permissions, models.Permission{ID: uuid.New(), Name: permission})
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// mockRefreshTokenRepository menyimpan refresh token di map
+type mockRefreshTokenRepository struct {
+	tokens map[uuid.UUID]*models.RefreshToken
+}
+
+func newMockRefreshTokenRepository() *mockRefreshTokenRepository {
+	return &mockRefreshTokenRepository{tokens: map[uuid.UUID]*models.RefreshToken{}}
+}
+
+func (m *mockRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	m.tokens[token.ID] = token
+	return nil
+}
+
+func (m *mockRefreshTokenRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.RefreshToken, error) {
+	token, ok := m.tokens[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *mockRefreshTokenRepository) Rotate(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) (bool, error) {
+	old, ok := m.tokens[oldID]
+	if !ok || old.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	old.RevokedAt = &now
+	old.ReplacedByID = &next.ID
+	m.tokens[next.ID] = next
+	return true, nil
+}
+
+func (m *mockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *mockRefreshTokenRepository) RevokeByUser(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
 func TestRegister_Success(t *testing.T) {
 	mockRepo := &mockAuthRepository{
 		mockRegister: func(context context.Context, user *models.User) error {
@@ -47,13 +234,13 @@ func TestRegister_Success(t *testing.T) {
 		},
 	}
 
-	service := NewAuthService(mockRepo)
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
 
 	user := models.User{
 		ID: uuid.New(),
 		Name: "Taufik",
 		Email: "taufik@dev.com",
-		Password: "1234567890",
+		Password: "rahasia123",
 	}
 	err := service.Register(context.Background(), &user)
 
@@ -62,6 +249,27 @@ func TestRegister_Success(t *testing.T) {
 	}
 }
 
+func TestRegister_WeakPassword(t *testing.T) {
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+
+	err := service.Register(context.Background(), &models.User{Name: "Taufik", Email: "taufik@dev.com", Password: "taufikkk"})
+
+	// setiap aturan yang dilanggar dilaporkan sebagai error field password
+	assert.ErrorIs(t, err, ErrValidation)
+	var rules []string
+	for _, field := range AsError(err).Fields {
+		assert.Equal(t, "password", field.Field)
+		rules = append(rules, field.Rule)
+	}
+	assert.Equal(t, []string{password.RuleBanned, password.RuleEntropy}, rules)
+}
+
 func TestRegister_Error(t *testing.T) {
 	mockRepo := &mockAuthRepository{
 		mockRegister: func(context context.Context, user *models.User) error {
@@ -77,7 +285,7 @@ func TestRegister_Error(t *testing.T) {
 		},
 	}
 
-	service := NewAuthService(mockRepo)
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
 
 	user := models.User{
 		ID: uuid.New(),
@@ -109,14 +317,14 @@ func TestLogin_Success(t *testing.T) {
 		},
 	}
 
-	service := NewAuthService(mockRepo)
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
 
 	request := &models.User{
 		Email: "taufik@dev.com",
 		Password: "1234567890",
 	}
 
-	accessToken, refreshToken, err := service.Login(context.Background(), request.Email, request.Password)
+	accessToken, refreshToken, err := loginTokens(service.Login(context.Background(), request.Email, request.Password))
 
 	if err != nil {
 		t.Fatalf("expected no error, but get %v", err)
@@ -143,14 +351,14 @@ func TestLogin_Error(t *testing.T) {
 		},
 	}
 
-	service := NewAuthService(mockRepo)
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
 
 	request := &models.User{
 		Email: "taufik@dev.com",
 		Password: "1234567890",
 	}
 
-	accessToken, refreshToken, err := service.Login(context.Background(), request.Email, request.Password)
+	accessToken, refreshToken, err := loginTokens(service.Login(context.Background(), request.Email, request.Password))
 
 	if err != nil {
 		assert.Equal(t, "invalid credentials", err.Error())
@@ -160,6 +368,158 @@ func TestLogin_Error(t *testing.T) {
 	assert.Empty(t, refreshToken)
 }
 
+func TestLogin_RehashesBcryptPassword(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("rahasia123")
+	user := &models.User{ID: uuid.New(), Email: "taufik@dev.com", Password: hashedPassword}
+
+	var newHash string
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return user, nil
+		},
+		mockUpdatePassword: func(ctx context.Context, id uuid.UUID, passwordHash string) error {
+			newHash = passwordHash
+			return nil
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+
+	_, _, err := loginTokens(service.Login(context.Background(), user.Email, "rahasia123"))
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(newHash, "$argon2id$"))
+	assert.True(t, crypto.CheckPasswordHash("rahasia123", newHash))
+
+	// hash yang sudah baru tidak di-hash ulang
+	newHash = ""
+	_, _, err = loginTokens(service.Login(context.Background(), user.Email, "rahasia123"))
+
+	assert.NoError(t, err)
+	assert.Empty(t, newHash)
+}
+
+func TestLogin_UnknownEmail(t *testing.T) {
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+
+	_, err := service.Login(context.Background(), "nobody@dev.com", "1234567890")
+
+	// email yang tidak terdaftar tidak boleh dibedakan dari password salah
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestLogin_LockoutAfterFailures(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("rahasia123")
+	user := &models.User{ID: uuid.New(), Email: "taufik@dev.com", Password: hashedPassword}
+
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return user, nil
+		},
+		mockFindByID: func(ctx context.Context, id uuid.UUID) (*models.User, error) {
+			return user, nil
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+	ctx := WithClientIP(context.Background(), "203.0.113.7")
+
+	for i := 0; i < service.Config.Lockout.MaxAttempts; i++ {
+		_, err := service.Login(ctx, user.Email, "salah")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// password yang benar tetap ditolak selama akun terkunci
+	_, err := service.Login(ctx, user.Email, "rahasia123")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Equal(t, service.Config.Lockout.BaseDelay, AsError(err).RetryAfter.Round(time.Second))
+
+	assert.NoError(t, service.Unlock(context.Background(), user.ID))
+
+	_, err = service.Login(ctx, user.Email, "rahasia123")
+	assert.NoError(t, err)
+}
+
+func TestLogin_LockoutPerIP(t *testing.T) {
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+	service.Lockout.Config.IPMaxAttempts = 3
+	ctx := WithClientIP(context.Background(), "203.0.113.7")
+
+	// setiap percobaan memakai email berbeda, hanya IP yang sama
+	for i := 0; i < 3; i++ {
+		_, err := service.Login(ctx, fmt.Sprintf("user%d@dev.com", i), "salah")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	_, err := service.Login(ctx, "other@dev.com", "salah")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	_, err = service.Login(WithClientIP(context.Background(), "198.51.100.1"), "other@dev.com", "salah")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestLogin_LockoutIgnoresIPByDefault(t *testing.T) {
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+	ctx := WithClientIP(context.Background(), "203.0.113.7")
+
+	_, err := service.Login(ctx, "nobody@dev.com", "salah")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// Tanpa IPMaxAttempts, IP proxy tidak boleh jadi satu ember untuk semua
+	_, err = service.Lockout.Repository.Find(ctx, ipKey("203.0.113.7"))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestLogin_LockoutForgetsStaleKeys(t *testing.T) {
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+	service.Lockout.Config.Window = 10 * time.Millisecond
+	ctx := context.Background()
+
+	service.Login(ctx, "made-up-1@dev.com", "salah")
+	time.Sleep(20 * time.Millisecond)
+	service.Login(ctx, "made-up-2@dev.com", "salah")
+
+	// Email karangan yang sudah lewat window tidak disimpan terus
+	_, err := service.Lockout.Repository.Find(ctx, accountKey("made-up-1@dev.com"))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = service.Lockout.Repository.Find(ctx, accountKey("made-up-2@dev.com"))
+	assert.NoError(t, err)
+}
+
+func TestLockoutDelay(t *testing.T) {
+	l := &lockout{Config: config.LockoutConfig{BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute}}
+
+	assert.Equal(t, 30*time.Second, l.delay(0))
+	assert.Equal(t, 60*time.Second, l.delay(1))
+	assert.Equal(t, 4*time.Minute, l.delay(3))
+	assert.Equal(t, 5*time.Minute, l.delay(4))
+	assert.Equal(t, 5*time.Minute, l.delay(100))
+}
+
 func TestMe_Success(t *testing.T) {
 	expectedID := uuid.New()
 
@@ -176,7 +536,7 @@ func TestMe_Success(t *testing.T) {
 		},
 	}
 
-	service := NewAuthService(mockRepo)
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
 
 	user, err := service.Me(context.Background(), expectedID.String())
 	if err != nil {
@@ -203,7 +563,7 @@ func TestMe_Error(t *testing.T) {
 		},
 	}
 
-	service := NewAuthService(mockRepo)
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
 
 	user, err := service.Me(context.Background(), expectedID.String())
 	
@@ -233,20 +593,20 @@ func TestRefresh_Success(t *testing.T) {
 		},
 	}
 
-	service := NewAuthService(mockRepo)
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
 
 	request := &models.User{
 		Email: "taufik@dev.com",
 		Password: "1234567890",
 	}
 
-	_, refreshToken, err := service.Login(context.Background(), request.Email, request.Password)
+	_, refreshToken, err := loginTokens(service.Login(context.Background(), request.Email, request.Password))
 
 	if err != nil {
 		t.Fatalf("expected no error, but get %v", err)
 	}
 
-	accessToken, err := service.Refresh(context.Background(), refreshToken)
+	accessToken, _, err := service.Refresh(context.Background(), refreshToken)
 
 	if err != nil {
 		t.Fatalf("expected no error, but get %v", err)
@@ -274,14 +634,14 @@ func TestRefresh_Test(t *testing.T) {
 		},
 	}
 
-	service := NewAuthService(mockRepo)
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
 
 	request := &models.User{
 		Email: "taufik@dev.com",
 		Password: "1234567890",
 	}
 
-	_, refreshToken, err := service.Login(context.Background(), request.Email, request.Password)
+	_, refreshToken, err := loginTokens(service.Login(context.Background(), request.Email, request.Password))
 
 	
 	if err != nil {
@@ -290,11 +650,467 @@ func TestRefresh_Test(t *testing.T) {
 	// Invalid refresh token	
 	refreshToken = "abcde"
 
-	accessToken, err := service.Refresh(context.Background(), refreshToken)
+	accessToken, _, err := service.Refresh(context.Background(), refreshToken)
+
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.Equal(t, KindUnauthorized, AsError(err).Kind)
+	assert.Empty(t, accessToken)
+}
+
+func TestRefresh_RotationAndReuse(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("1234567890")
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return &models.User{
+				ID: uuid.New(),
+				Email: "taufik@dev.com",
+				Password: hashedPassword,
+			}, nil
+		},
+	}
+
+	tokenRepo := newMockRefreshTokenRepository()
+	service := newTestAuthService(mockRepo, tokenRepo)
+
+	_, firstToken, err := loginTokens(service.Login(context.Background(), "taufik@dev.com", "1234567890"))
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+
+	_, secondToken, err := service.Refresh(context.Background(), firstToken)
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+	assert.NotEqual(t, firstToken, secondToken)
+
+	// Token lama dipakai lagi, seluruh family harus dicabut
+	_, _, err = service.Refresh(context.Background(), firstToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
 
+	_, _, err = service.Refresh(context.Background(), secondToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+}
+
+func TestLogout_RevokesToken(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("1234567890")
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return &models.User{
+				ID: uuid.New(),
+				Email: "taufik@dev.com",
+				Password: hashedPassword,
+			}, nil
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+
+	_, refreshToken, err := loginTokens(service.Login(context.Background(), "taufik@dev.com", "1234567890"))
 	if err != nil {
-		assert.Equal(t, "invalid or expired refresh token", err.Error())
+		t.Fatalf("expected no error, but get %v", err)
+	}
+
+	if err := service.Logout(context.Background(), refreshToken); err != nil {
+		t.Fatalf("expected no error, but get %v", err)
 	}
 
+	accessToken, _, err := service.Refresh(context.Background(), refreshToken)
+	assert.Error(t, err)
 	assert.Empty(t, accessToken)
-}
\ No newline at end of file
+}
+
+func TestAssignRoles_Success(t *testing.T) {
+	userID := uuid.New()
+	mockRepo := &mockAuthRepository{
+		mockFindByID: func(ctx context.Context, id uuid.UUID) (*models.User, error) {
+			return &models.User{ID: id, Name: "Taufik"}, nil
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+
+	user, err := service.AssignRoles(context.Background(), userID, []string{models.RoleAdmin, models.RoleCustomer})
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+
+	assert.ElementsMatch(t, []string{models.RoleAdmin, models.RoleCustomer}, user.RoleNames())
+	assert.Contains(t, user.PermissionNames(), models.PermissionRolesAssign)
+}
+
+func TestAssignRoles_UnknownRole(t *testing.T) {
+	mockRepo := &mockAuthRepository{}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+
+	user, err := service.AssignRoles(context.Background(), uuid.New(), []string{"superuser"})
+
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+	assert.Nil(t, user)
+}
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+
+	err := service.ForgotPassword(context.Background(), "nobody@dev.com")
+
+	assert.NoError(t, err)
+	assert.Empty(t, service.Mailer.(*mockMailer).messages)
+}
+
+func TestForgotPassword_MailFailureLooksLikeSuccess(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "taufik@dev.com"}
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return user, nil
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+	service.Mailer = &mockMailer{err: errors.New("smtp down")}
+
+	// Email terdaftar tidak boleh bisa dibedakan dari status error
+	assert.NoError(t, service.ForgotPassword(context.Background(), user.Email))
+	assert.NoError(t, service.ResendVerification(context.Background(), user.Email))
+}
+
+func TestForgotPassword_LogSenderHidesToken(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "taufik@dev.com"}
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return user, nil
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+	require.NoError(t, service.ForgotPassword(context.Background(), user.Email))
+
+	messages := service.Mailer.(*mockMailer).messages
+	require.Len(t, messages, 1)
+	token := regexp.MustCompile(`token=([\w-]+)`).FindStringSubmatch(messages[0].Body)
+	require.Len(t, token, 2)
+
+	// email reset yang dicatat driver log tidak boleh membocorkan token
+	var out bytes.Buffer
+	logger := logging.New(config.LogConfig{Level: "debug", Format: config.LogJSON}, &out)
+	require.NoError(t, mail.LogSender{Logger: logger}.Send(context.Background(), messages[0]))
+
+	assert.Contains(t, out.String(), user.Email)
+	assert.NotContains(t, out.String(), token[1])
+}
+
+func TestResetPassword_Success(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("1234567890")
+	user := &models.User{ID: uuid.New(), Email: "taufik@dev.com", Password: hashedPassword}
+
+	var newHash string
+	mockRepo := &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return user, nil
+		},
+		mockUpdatePassword: func(ctx context.Context, id uuid.UUID, passwordHash string) error {
+			newHash = passwordHash
+			return nil
+		},
+	}
+
+	tokenRepo := newMockRefreshTokenRepository()
+	service := newTestAuthService(mockRepo, tokenRepo)
+
+	_, refreshToken, err := loginTokens(service.Login(context.Background(), user.Email, "1234567890"))
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+
+	if err := service.ForgotPassword(context.Background(), user.Email); err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+
+	messages := service.Mailer.(*mockMailer).messages
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 email, but get %d", len(messages))
+	}
+	_, token, found := strings.Cut(messages[0].Body, "token=")
+	if !found {
+		t.Fatalf("expected reset link in email, but get %q", messages[0].Body)
+	}
+	token = strings.Fields(token)[0]
+
+	err = service.ResetPassword(context.Background(), token, "new-password")
+
+	assert.NoError(t, err)
+	assert.True(t, crypto.CheckPasswordHash("new-password", newHash))
+
+	// Sesi lama harus dicabut
+	_, _, err = service.Refresh(context.Background(), refreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	// Token reset hanya bisa dipakai sekali
+	err = service.ResetPassword(context.Background(), token, "another-password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestChangePassword(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("rahasia123")
+	user := &models.User{ID: uuid.New(), Name: "Taufik", Email: "taufik@dev.com", Password: hashedPassword}
+
+	var newHash string
+	mockRepo := &mockAuthRepository{
+		mockFindByID: func(ctx context.Context, id uuid.UUID) (*models.User, error) {
+			return user, nil
+		},
+		mockUpdatePassword: func(ctx context.Context, id uuid.UUID, passwordHash string) error {
+			newHash = passwordHash
+			return nil
+		},
+	}
+
+	tokenRepo := newMockRefreshTokenRepository()
+	service := newTestAuthService(mockRepo, tokenRepo)
+
+	err := service.ChangePassword(context.Background(), user.ID, "salah", "kopi-susu-gula-aren")
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Equal(t, "currentPassword", AsError(err).Fields[0].Field)
+
+	err = service.ChangePassword(context.Background(), user.ID, "rahasia123", "password")
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Empty(t, newHash)
+
+	err = service.ChangePassword(context.Background(), user.ID, "rahasia123", "kopi-susu-gula-aren")
+	assert.NoError(t, err)
+	assert.True(t, crypto.CheckPasswordHash("kopi-susu-gula-aren", newHash))
+}
+
+func TestChangePassword_Lockout(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("rahasia123")
+	user := &models.User{ID: uuid.New(), Name: "Taufik", Email: "taufik@dev.com", Password: hashedPassword}
+
+	mockRepo := &mockAuthRepository{
+		mockFindByID: func(ctx context.Context, id uuid.UUID) (*models.User, error) {
+			return user, nil
+		},
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return user, nil
+		},
+		mockUpdatePassword: func(ctx context.Context, id uuid.UUID, passwordHash string) error {
+			return nil
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+
+	for i := 0; i < service.Config.Lockout.MaxAttempts; i++ {
+		err := service.ChangePassword(context.Background(), user.ID, "salah", "kopi-susu-gula-aren")
+		assert.ErrorIs(t, err, ErrValidation)
+	}
+
+	// password lama yang benar tetap ditolak selama akun terkunci
+	err := service.ChangePassword(context.Background(), user.ID, "rahasia123", "kopi-susu-gula-aren")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	// login ke akun yang sama juga terkunci
+	_, err = service.Login(context.Background(), user.Email, "rahasia123")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+}
+
+func TestResetPassword_Expired(t *testing.T) {
+	service := newTestAuthService(&mockAuthRepository{}, newMockRefreshTokenRepository())
+
+	service.ResetRepository.Create(context.Background(), &models.PasswordResetToken{
+		ID: uuid.New(),
+		UserID: uuid.New(),
+		TokenHash: crypto.HashToken("expired-token"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+
+	err := service.ResetPassword(context.Background(), "expired-token", "new-password")
+
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestVerifyEmail_Success(t *testing.T) {
+	var verified bool
+	mockRepo := &mockAuthRepository{
+		mockRegister: func(ctx context.Context, user *models.User) error {
+			return nil
+		},
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		mockMarkEmailVerified: func(ctx context.Context, id uuid.UUID) error {
+			verified = true
+			return nil
+		},
+	}
+
+	service := newTestAuthService(mockRepo, newMockRefreshTokenRepository())
+
+	err := service.Register(context.Background(), &models.User{Name: "Taufik", Email: "taufik@dev.com", Password: "rahasia123"})
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+
+	messages := service.Mailer.(*mockMailer).messages
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 email, but get %d", len(messages))
+	}
+	_, token, _ := strings.Cut(messages[0].Body, "token=")
+	token = strings.Fields(token)[0]
+
+	assert.NoError(t, service.VerifyEmail(context.Background(), token))
+	assert.True(t, verified)
+	assert.ErrorIs(t, service.VerifyEmail(context.Background(), token), ErrInvalidVerificationToken)
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	service := newTestAuthService(&mockAuthRepository{}, newMockRefreshTokenRepository())
+
+	err := service.VerifyEmail(context.Background(), "unknown-token")
+
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+}
+
+// singleUserRepository menyimpan satu user agar alur yang membaca dan
+// mengubah user yang sama bisa diuji end to end
+func singleUserRepository(user *models.User) *mockAuthRepository {
+	return &mockAuthRepository{
+		mockFindByEmail: func(ctx context.Context, email string) (*models.User, error) {
+			copied := *user
+			return &copied, nil
+		},
+		mockFindByID: func(ctx context.Context, id uuid.UUID) (*models.User, error) {
+			copied := *user
+			return &copied, nil
+		},
+		mockUpdateMFA: func(ctx context.Context, id uuid.UUID, secret string, enabledAt *time.Time) error {
+			user.MFASecret = secret
+			user.MFAEnabledAt = enabledAt
+			return nil
+		},
+		mockUseMFACounter: func(ctx context.Context, id uuid.UUID, counter uint64) (bool, error) {
+			if user.MFALastCounter >= counter {
+				return false, nil
+			}
+			user.MFALastCounter = counter
+			return true, nil
+		},
+	}
+}
+
+func TestMFA_EnrollConfirmAndLogin(t *testing.T) {
+	hashedPassword, _ := crypto.HashPassword("1234567890")
+	user := &models.User{ID: uuid.New(), Email: "taufik@dev.com", Password: hashedPassword}
+
+	service := newTestAuthService(singleUserRepository(user), newMockRefreshTokenRepository())
+	ctx := context.Background()
+
+	enrollment, err := service.EnrollMFA(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+	assert.Contains(t, enrollment.URI, "otpauth://totp/")
+
+	_, err = service.ConfirmMFA(ctx, user.ID, "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	code, _ := totp.Code(enrollment.Secret, time.Now())
+	recoveryCodes, err := service.ConfirmMFA(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+	assert.Len(t, recoveryCodes, recoveryCodeCount)
+	assert.True(t, user.MFAEnabled())
+
+	result, err := service.Login(ctx, user.Email, "1234567890")
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+	assert.Empty(t, result.AccessToken)
+	assert.NotEmpty(t, result.MFAToken)
+
+	// Token MFA tidak boleh dipakai sebagai access token
+	_, err = testTokens().ValidateAccessToken(result.MFAToken)
+	assert.Error(t, err)
+
+	_, err = service.VerifyMFA(ctx, result.MFAToken, "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	// Kode konfirmasi tidak boleh dipakai ulang selama masih dalam skew
+	_, err = service.VerifyMFA(ctx, result.MFAToken, code)
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	code, _ = totp.Code(enrollment.Secret, time.Now().Add(totp.Period))
+	tokens, err := service.VerifyMFA(ctx, result.MFAToken, code)
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	// Token MFA hanya bisa ditukar sekali
+	_, err = service.VerifyMFA(ctx, result.MFAToken, recoveryCodes[0])
+	assert.ErrorIs(t, err, ErrInvalidMFAToken)
+}
+
+func TestMFA_LockoutOnWrongCodes(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "taufik@dev.com"}
+
+	service := newTestAuthService(singleUserRepository(user), newMockRefreshTokenRepository())
+	ctx := context.Background()
+
+	enrollment, err := service.EnrollMFA(ctx, user.ID)
+	require.NoError(t, err)
+
+	for i := 0; i < service.Config.Lockout.MaxAttempts; i++ {
+		_, err = service.ConfirmMFA(ctx, user.ID, "000000")
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+	}
+
+	// kode yang benar tetap ditolak selama akun terkunci
+	code, _ := totp.Code(enrollment.Secret, time.Now())
+	_, err = service.ConfirmMFA(ctx, user.ID, code)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	require.NoError(t, service.Unlock(ctx, user.ID))
+	_, err = service.ConfirmMFA(ctx, user.ID, code)
+	require.NoError(t, err)
+
+	// sesi yang dicuri tidak boleh menebak kode untuk mematikan MFA
+	for i := 0; i < service.Config.Lockout.MaxAttempts; i++ {
+		err = service.DisableMFA(ctx, user.ID, "000000")
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+	}
+
+	code, _ = totp.Code(enrollment.Secret, time.Now().Add(totp.Period))
+	err = service.DisableMFA(ctx, user.ID, code)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.True(t, user.MFAEnabled())
+}
+
+func TestMFA_RecoveryCodeIsSingleUse(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "taufik@dev.com"}
+
+	service := newTestAuthService(singleUserRepository(user), newMockRefreshTokenRepository())
+	ctx := context.Background()
+
+	enrollment, _ := service.EnrollMFA(ctx, user.ID)
+	code, _ := totp.Code(enrollment.Secret, time.Now())
+	recoveryCodes, err := service.ConfirmMFA(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("expected no error, but get %v", err)
+	}
+
+	mfaToken, _ := testTokens().GenerateMFAToken(user.ID.String())
+
+	_, err = service.VerifyMFA(ctx, mfaToken, strings.ToLower(recoveryCodes[0]))
+	assert.NoError(t, err)
+
+	_, err = service.VerifyMFA(ctx, mfaToken, recoveryCodes[0])
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+// Kind classifies an Error. Handlers map every kind to one HTTP status.
+type Kind string
+
+const (
+	KindInvalid			Kind = "invalid"
+	KindUnauthorized	Kind = "unauthorized"
+	KindForbidden		Kind = "forbidden"
+	KindNotFound		Kind = "not_found"
+	KindConflict		Kind = "conflict"
+	KindTooManyRequests	Kind = "too_many_requests"
+	KindInternal		Kind = "internal"
+)
+
+// Error is a domain error with a stable, machine readable Code. Message is
+// safe to show to clients, Err keeps the underlying cause for logs only.
+type Error struct {
+	Kind		Kind
+	Code		string
+	Message		string
+	Fields		[]FieldError
+	Err			error
+	// RetryAfter tells clients how long to wait before trying again.
+	RetryAfter	time.Duration
+}
+
+// FieldError describes why one field of a request was rejected.
+type FieldError struct {
+	Field	string	`json:"field"`
+	Rule	string	`json:"rule"`
+	Message	string	`json:"message"`
+}
+
+// Validation returns an ErrValidation carrying the rejected fields.
+func Validation(fields ...FieldError) *Error {
+	err := *ErrValidation
+	err.Fields = fields
+	return &err
+}
+
+func NewError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Internal wraps an unexpected error. Its cause is never sent to clients.
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "internal server error", Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors with the same code, so errors.Is works on copies made
+// by Wrap.
+func (e *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other.Code == e.Code
+}
+
+// Wrap returns a copy of e that keeps err as its cause.
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+// RetryIn returns a copy of e that asks clients to retry after d.
+func (e *Error) RetryIn(d time.Duration) *Error {
+	copied := *e
+	copied.RetryAfter = d
+	return &copied
+}
+
+// AsError converts any error into an *Error, unknown errors become internal.
+func AsError(err error) *Error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr
+	}
+	return Internal(err)
+}
+
+var (
+	ErrInvalidRequest	= NewError(KindInvalid, "invalid_request", "invalid request!")
+	ErrValidation		= NewError(KindInvalid, "validation_failed", "request has invalid fields")
+	ErrUnauthorized		= NewError(KindUnauthorized, "unauthorized", "Unauthorized or userID not found in context")
+	ErrInvalidToken		= NewError(KindUnauthorized, "invalid_token", "Invalid or expired token")
+	ErrMissingToken		= NewError(KindUnauthorized, "missing_token", "Missing or malformed token")
+	ErrForbidden		= NewError(KindForbidden, "forbidden", "you are not allowed to do this")
+	ErrInvalidID		= NewError(KindInvalid, "invalid_id", "id is not valid uuid")
+	ErrRateLimited		= NewError(KindTooManyRequests, "rate_limited", "too many requests, slow down")
+)
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_IsMatchesCode(t *testing.T) {
+	cause := errors.New("token is expired")
+	err := ErrInvalidRefreshToken.Wrap(cause)
+
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, ErrRefreshTokenReused)
+	assert.Equal(t, "invalid or expired refresh token", err.Error())
+}
+
+func TestAsError_HidesUnknownErrors(t *testing.T) {
+	err := AsError(errors.New("pq: connection refused"))
+
+	assert.Equal(t, KindInternal, err.Kind)
+	assert.Equal(t, "internal_error", err.Code)
+	assert.Equal(t, "internal server error", err.Message)
+
+	assert.Same(t, ErrProductNotFound, AsError(ErrProductNotFound))
+}
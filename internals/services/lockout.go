@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"gorm.io/gorm"
+)
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the IP address of the client,
+// failed logins are counted against it as well as against the account.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the IP address stored by WithClientIP, if any.
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// lockout throttles password and second factor guessing per account and per
+// client IP address.
+type lockout struct {
+	Repository	repository.LoginAttemptRepository
+	Config		config.LockoutConfig
+}
+
+// limits returns the keys of a login for email and their number of allowed
+// failures. The client IP only counts when IPMaxAttempts is set.
+func (l *lockout) limits(ctx context.Context, email string) map[string]int {
+	limits := map[string]int{accountKey(email): l.Config.MaxAttempts}
+	if ip := ClientIP(ctx); ip != "" && l.Config.IPMaxAttempts > 0 {
+		limits[ipKey(ip)] = l.Config.IPMaxAttempts
+	}
+	return limits
+}
+
+// check returns ErrTooManyAttempts while the account or the client is locked.
+func (l *lockout) check(ctx context.Context, email string) error {
+	now := time.Now()
+
+	for key := range l.limits(ctx, email) {
+		attempt, err := l.Repository.Find(ctx, key)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return Internal(err)
+		}
+
+		if attempt.Locked(now) {
+			return ErrTooManyAttempts.RetryIn(attempt.LockedUntil.Sub(now))
+		}
+	}
+
+	return nil
+}
+
+// fail counts a failed login and locks the keys that reached their limit.
+func (l *lockout) fail(ctx context.Context, email string) error {
+	for key, limit := range l.limits(ctx, email) {
+		failures, err := l.Repository.Fail(ctx, key, l.Config.Window)
+		if err != nil {
+			return Internal(err)
+		}
+
+		if failures < limit {
+			continue
+		}
+
+		if err := l.Repository.Lock(ctx, key, time.Now().Add(l.delay(failures-limit))); err != nil {
+			return Internal(err)
+		}
+	}
+
+	return nil
+}
+
+// reset forgets the failures of the account. The failures of the client IP
+// are kept, a valid login of their own must not let attackers guess on.
+func (l *lockout) reset(ctx context.Context, email string) error {
+	if err := l.Repository.Reset(ctx, accountKey(email)); err != nil {
+		return Internal(err)
+	}
+	return nil
+}
+
+// delay is the lockout after excess failures past the limit, doubling from
+// BaseDelay up to MaxDelay.
+func (l *lockout) delay(excess int) time.Duration {
+	delay := l.Config.BaseDelay
+	for i := 0; i < excess && delay < l.Config.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, l.Config.MaxDelay)
+}
@@ -2,51 +2,128 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"log/slog"
 
 	"github.com/google/uuid"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/dto"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProductNotFound	= NewError(KindNotFound, "product_not_found", "product not found")
+	ErrNotProductOwner	= NewError(KindForbidden, "not_product_owner", "you are not the owner of this product")
+	ErrInvalidCursor	= NewError(KindInvalid, "invalid_cursor", "invalid cursor")
+	ErrInvalidSort		= NewError(KindInvalid, "invalid_sort", "invalid sort field")
+	ErrInvalidUserID	= NewError(KindInvalid, "invalid_user_id", "invalid user id")
+)
+
+const (
+	DefaultProductLimit	= 20
+	MaxProductLimit		= 100
 )
 
 type ProductService interface {
-	GetProducts(ctx context.Context)([]dto.ProductResponse, error)
+	GetProducts(ctx context.Context, query dto.ProductQuery)([]dto.ProductResponse, *dto.PageMeta, error)
 	GetProduct(ctx context.Context, id uuid.UUID) (*models.Product, error)
 	CreateProduct(ctx context.Context, product *models.Product) error
+	UpdateProduct(ctx context.Context, id, userID uuid.UUID, input dto.ProductPatchRequest) (*models.Product, error)
+	DeleteProduct(ctx context.Context, id, userID uuid.UUID) error
 }
 
 type productService struct {
-	Repository 		repository.ProductRepository
-	UserRepository 	repository.UserRepository
+	Repository 				repository.ProductRepository
+	UserRepository 			repository.UserRepository
+	// RequireVerifiedEmail refuses products from users with an unverified email.
+	RequireVerifiedEmail	bool
+	Logger					*slog.Logger
+	Tracer					trace.Tracer
 }
 
-func NewProductService(repository repository.ProductRepository, userRepository repository.UserRepository) *productService {
+func NewProductService(repository repository.ProductRepository, userRepository repository.UserRepository, requireVerifiedEmail bool, logger *slog.Logger, tracer trace.Tracer) *productService {
 	return &productService{
 		Repository: repository,
 		UserRepository: userRepository,
+		RequireVerifiedEmail: requireVerifiedEmail,
+		Logger: logger,
+		Tracer: tracer,
 	}
 }
 
-func (s *productService) CreateProduct(ctx context.Context, product *models.Product ) error {
-	if _, err := uuid.Parse(product.UserID.String()); err != nil {
-		return errors.New("invalid user id")
+func (s *productService) CreateProduct(ctx context.Context, product *models.Product ) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "ProductService.CreateProduct")
+	defer func() { tracing.End(span, err) }()
+
+	if product.UserID == uuid.Nil {
+		return ErrInvalidUserID
 	}
 
-	_, err := s.UserRepository.FindByID(ctx, product.UserID); 
+	user, err := s.UserRepository.FindByID(ctx, product.UserID); 
 	if err != nil {
-		return errors.New("user not found")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return Internal(err)
 	}
 
-	return s.Repository.Create(ctx, product)
+	if s.RequireVerifiedEmail && !user.EmailVerified() {
+		return ErrEmailNotVerified
+	}
+
+	if err := s.Repository.Create(ctx, product); err != nil {
+		return Internal(err)
+	}
+
+	s.Logger.InfoContext(ctx, "product created", "product_id", product.ID.String())
+	return nil
 }
 
-func (s *productService) GetProducts(ctx context.Context) ([]dto.ProductResponse, error) {
+func (s *productService) GetProducts(ctx context.Context, query dto.ProductQuery) (_ []dto.ProductResponse, _ *dto.PageMeta, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "ProductService.GetProducts")
+	defer func() { tracing.End(span, err) }()
+
 	var productResp []dto.ProductResponse
 
-	products, err := s.Repository.FindAll(ctx)
+	filter, err := productFilter(query)
 	if err != nil {
-		return productResp, err
+		return productResp, nil, err
+	}
+
+	// Fetch one extra product to know whether another page exists.
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	products, total, err := s.Repository.FindAll(ctx, filter)
+	if err != nil {
+		return productResp, nil, Internal(err)
+	}
+
+	meta := &dto.PageMeta{
+		Total: total,
+		Limit: limit,
+	}
+
+	if query.Cursor == "" {
+		meta.Page = filter.Offset/limit + 1
+	}
+
+	if len(products) > limit {
+		products = products[:limit]
+		meta.HasMore = true
+
+		last := products[len(products)-1]
+		meta.NextCursor = encodeCursor(repository.ProductCursor{
+			ID: last.ID,
+			Name: last.Name,
+			Price: last.Price,
+			CreatedAt: last.CreatedAt,
+		})
 	}
 
 	for _, product := range products {
@@ -54,13 +131,141 @@ func (s *productService) GetProducts(ctx context.Context) ([]dto.ProductResponse
 			ID: product.ID,
 			Name: product.Name,
 			Price: product.Price,
+			UserID: product.UserID,
 			CreatedAt: product.CreatedAt,
+			UpdatedAt: product.UpdatedAt,
 		})
 	}
 
-	return productResp, nil
+	return productResp, meta, nil
+}
+
+func (s *productService) GetProduct(ctx context.Context, id uuid.UUID) (_ *models.Product, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "ProductService.GetProduct")
+	defer func() { tracing.End(span, err) }()
+
+	product, err := s.Repository.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, Internal(err)
+	}
+
+	return product, nil
+}
+
+func (s *productService) UpdateProduct(ctx context.Context, id, userID uuid.UUID, input dto.ProductPatchRequest) (_ *models.Product, err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "ProductService.UpdateProduct")
+	defer func() { tracing.End(span, err) }()
+
+	product, err := s.findOwnedProduct(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		product.Name = *input.Name
+	}
+
+	if input.Price != nil {
+		product.Price = *input.Price
+	}
+
+	if err := s.Repository.Update(ctx, product); err != nil {
+		return nil, Internal(err)
+	}
+
+	s.Logger.InfoContext(ctx, "product updated", "product_id", product.ID.String())
+	return product, nil
+}
+
+func (s *productService) DeleteProduct(ctx context.Context, id, userID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, s.Tracer, "ProductService.DeleteProduct")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.findOwnedProduct(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.Repository.Delete(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProductNotFound
+		}
+		return Internal(err)
+	}
+
+	s.Logger.InfoContext(ctx, "product deleted", "product_id", id.String())
+	return nil
 }
 
-func (s *productService) GetProduct(ctx context.Context, id uuid.UUID) (*models.Product, error){
-	return s.Repository.FindByID(ctx, id)
-}
\ No newline at end of file
+// findOwnedProduct loads the product and makes sure it belongs to userID.
+func (s *productService) findOwnedProduct(ctx context.Context, id, userID uuid.UUID) (*models.Product, error) {
+	product, err := s.GetProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.UserID != userID {
+		return nil, ErrNotProductOwner
+	}
+
+	return product, nil
+}
+
+func productFilter(query dto.ProductQuery) (repository.ProductFilter, error) {
+	filter := repository.ProductFilter{
+		Search: query.Search,
+		MinPrice: query.MinPrice,
+		MaxPrice: query.MaxPrice,
+		UserID: query.UserID,
+		SortField: query.Sort,
+		Desc: query.Order != "asc",
+		Limit: query.Limit,
+	}
+
+	if filter.SortField == "" {
+		filter.SortField = "createdAt"
+	}
+	if _, ok := repository.ProductSortFields[filter.SortField]; !ok {
+		return filter, ErrInvalidSort
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultProductLimit
+	}
+	if filter.Limit > MaxProductLimit {
+		filter.Limit = MaxProductLimit
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return filter, ErrInvalidCursor
+		}
+		filter.After = cursor
+	} else if query.Page > 1 {
+		filter.Offset = (query.Page - 1) * filter.Limit
+	}
+
+	return filter, nil
+}
+
+func encodeCursor(cursor repository.ProductCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (*repository.ProductCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor repository.ProductCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
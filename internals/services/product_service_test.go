@@ -4,16 +4,28 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/dto"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/logging"
 	"github.com/iamtaufik/golang-vercel-deployment/internals/models"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
 )
 
 // mockProductRepository adalah mock manual yang implement ProductRepository
 type mockProductRepository struct {
-	mockFindAll func(ctx context.Context) ([]models.Product, error)
+	mockFindAll func(ctx context.Context, filter repository.ProductFilter) ([]models.Product, int64, error)
 	mockGetByID func(ctx context.Context, id uuid.UUID) (*models.Product, error)
 	mockCreate func(ctx context.Context, product *models.Product) error
+	mockUpdate func(ctx context.Context, product *models.Product) error
+	mockDelete func(ctx context.Context, id uuid.UUID) error
 }
 
 type mockUserRepository struct {
@@ -30,6 +42,27 @@ func (m *mockUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*model
 	return m.mockFindByID(ctx, id)
 }
 
+func (m *mockUserRepository) SetRoles(ctx context.Context, user *models.User, roles []models.Role) error {
+	user.Roles = roles
+	return nil
+}
+
+func (m *mockUserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	return nil
+}
+
+func (m *mockUserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (m *mockUserRepository) UpdateMFA(ctx context.Context, id uuid.UUID, secret string, enabledAt *time.Time) error {
+	return nil
+}
+
+func (m *mockUserRepository) UseMFACounter(ctx context.Context, id uuid.UUID, counter uint64) (bool, error) {
+	return true, nil
+}
+
 func (m *mockUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	if m.mockFindByEmail != nil {
 		return m.mockFindByEmail(ctx, email)
@@ -37,8 +70,8 @@ func (m *mockUserRepository) FindByEmail(ctx context.Context, email string) (*mo
 	return nil, nil
 }
 
-func (m *mockProductRepository) FindAll(ctx context.Context) ([]models.Product, error) {
-	return m.mockFindAll(ctx)
+func (m *mockProductRepository) FindAll(ctx context.Context, filter repository.ProductFilter) ([]models.Product, int64, error) {
+	return m.mockFindAll(ctx, filter)
 }
 
 func (m *mockProductRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
@@ -48,6 +81,14 @@ func (m *mockProductRepository) Create(ctx context.Context, product *models.Prod
 	return m.mockCreate(ctx, product)
 }
 
+func (m *mockProductRepository) Update(ctx context.Context, product *models.Product) error {
+	return m.mockUpdate(ctx, product)
+}
+
+func (m *mockProductRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return m.mockDelete(ctx, id)
+}
+
 func TestCreateProduct_Success(t *testing.T) {
 	expectedUserID := uuid.New()
 
@@ -77,7 +118,7 @@ func TestCreateProduct_Success(t *testing.T) {
 	}
 
 
-	service := NewProductService(mockProductRepo, mockUserRepo)
+	service := NewProductService(mockProductRepo, mockUserRepo, false, logging.Discard(), tracing.Noop())
 
 	product := &models.Product{
 		ID: uuid.New(),
@@ -93,23 +134,44 @@ func TestCreateProduct_Success(t *testing.T) {
 	}
 }
 
+func TestCreateProduct_EmailNotVerified(t *testing.T) {
+	mockProductRepo := &mockProductRepository{
+		mockCreate: func(ctx context.Context, product *models.Product) error {
+			t.Fatal("product must not be created")
+			return nil
+		},
+	}
+
+	mockUserRepo := &mockUserRepository{
+		mockFindByID: func(ctx context.Context, id uuid.UUID) (*models.User, error) {
+			return &models.User{ID: id, Name: "Taufik", Email: "taufik@dev.com"}, nil
+		},
+	}
+
+	service := NewProductService(mockProductRepo, mockUserRepo, true, logging.Discard(), tracing.Noop())
+
+	err := service.CreateProduct(context.Background(), &models.Product{Name: "Product B", Price: 2000, UserID: uuid.New()})
+
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+}
+
 func TestGetProducts_Success(t *testing.T) {
 	// Arrange
 	mockRepo := &mockProductRepository{
-		mockFindAll: func(ctx context.Context) ([]models.Product, error) {
+		mockFindAll: func(ctx context.Context, filter repository.ProductFilter) ([]models.Product, int64, error) {
 			return []models.Product{
 				{ID: uuid.New(), Name: "Produk A", Price: 10000},
 				{ID: uuid.New(), Name: "Produk B", Price: 20000},
-			}, nil
+			}, 2, nil
 		},
 	}
 
 	mockUserRepo := &mockUserRepository{}
 
-	service := NewProductService(mockRepo, mockUserRepo)
+	service := NewProductService(mockRepo, mockUserRepo, false, logging.Discard(), tracing.Noop())
 
 	// Act
-	products, err := service.GetProducts(context.Background())
+	products, _, err := service.GetProducts(context.Background(), dto.ProductQuery{})
 
 	// Assert
 	if err != nil {
@@ -124,17 +186,17 @@ func TestGetProducts_Success(t *testing.T) {
 func TestGetProducts_Error(t *testing.T) {
 	// Arrange
 	mockRepo := &mockProductRepository{
-		mockFindAll: func(ctx context.Context) ([]models.Product, error) {
-			return nil, errors.New("database error")
+		mockFindAll: func(ctx context.Context, filter repository.ProductFilter) ([]models.Product, int64, error) {
+			return nil, 0, errors.New("database error")
 		},
 	}
 
 	mockUserRepo := &mockUserRepository{}
 
-	service := NewProductService(mockRepo, mockUserRepo)
+	service := NewProductService(mockRepo, mockUserRepo, false, logging.Discard(), tracing.Noop())
 
 	// Act
-	products, err := service.GetProducts(context.Background())
+	products, _, err := service.GetProducts(context.Background(), dto.ProductQuery{})
 
 	// Assert
 	if err == nil {
@@ -167,7 +229,7 @@ func TestGetProduct_Success(t *testing.T){
 
 	mockUserRepo := &mockUserRepository{}
 
-	service := NewProductService(mockRepo, mockUserRepo)
+	service := NewProductService(mockRepo, mockUserRepo, false, logging.Discard(), tracing.Noop())
 	
 	product, err := service.GetProduct(context.Background(), expectedID)
 
@@ -202,7 +264,7 @@ func TestGetProduct_Error(t *testing.T) {
 
 	mockUserRepo := &mockUserRepository{}
 
-	service := NewProductService(mockRepo, mockUserRepo)
+	service := NewProductService(mockRepo, mockUserRepo, false, logging.Discard(), tracing.Noop())
 	
 	product, err := service.GetProduct(context.Background(), expectedID)
 
@@ -214,3 +276,161 @@ func TestGetProduct_Error(t *testing.T) {
 		t.Errorf("expected nil products, got %+v", product)
 	}
 }
+
+func TestUpdateProduct_Success(t *testing.T) {
+	ownerID := uuid.New()
+	productID := uuid.New()
+
+	mockRepo := &mockProductRepository{
+		mockGetByID: func(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+			return &models.Product{ID: productID, Name: "Product A", Price: 1000, UserID: ownerID}, nil
+		},
+		mockUpdate: func(ctx context.Context, product *models.Product) error {
+			return nil
+		},
+	}
+
+	service := NewProductService(mockRepo, &mockUserRepository{}, false, logging.Discard(), tracing.Noop())
+
+	newPrice := 2500.0
+	product, err := service.UpdateProduct(context.Background(), productID, ownerID, dto.ProductPatchRequest{Price: &newPrice})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	assert.Equal(t, "Product A", product.Name)
+	assert.Equal(t, 2500.0, product.Price)
+}
+
+func TestUpdateProduct_NotOwner(t *testing.T) {
+	mockRepo := &mockProductRepository{
+		mockGetByID: func(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+			return &models.Product{ID: id, Name: "Product A", Price: 1000, UserID: uuid.New()}, nil
+		},
+		mockUpdate: func(ctx context.Context, product *models.Product) error {
+			t.Fatal("update must not be called for non owner")
+			return nil
+		},
+	}
+
+	service := NewProductService(mockRepo, &mockUserRepository{}, false, logging.Discard(), tracing.Noop())
+
+	newName := "Product B"
+	product, err := service.UpdateProduct(context.Background(), uuid.New(), uuid.New(), dto.ProductPatchRequest{Name: &newName})
+
+	assert.ErrorIs(t, err, ErrNotProductOwner)
+	assert.Nil(t, product)
+}
+
+func TestDeleteProduct_Success(t *testing.T) {
+	ownerID := uuid.New()
+	productID := uuid.New()
+	deleted := false
+
+	mockRepo := &mockProductRepository{
+		mockGetByID: func(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+			return &models.Product{ID: productID, UserID: ownerID}, nil
+		},
+		mockDelete: func(ctx context.Context, id uuid.UUID) error {
+			deleted = id == productID
+			return nil
+		},
+	}
+
+	service := NewProductService(mockRepo, &mockUserRepository{}, false, logging.Discard(), tracing.Noop())
+
+	err := service.DeleteProduct(context.Background(), productID, ownerID)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	assert.True(t, deleted)
+}
+
+func TestDeleteProduct_NotFound(t *testing.T) {
+	mockRepo := &mockProductRepository{
+		mockGetByID: func(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+
+	service := NewProductService(mockRepo, &mockUserRepository{}, false, logging.Discard(), tracing.Noop())
+
+	err := service.DeleteProduct(context.Background(), uuid.New(), uuid.New())
+
+	assert.ErrorIs(t, err, ErrProductNotFound)
+}
+
+func TestGetProducts_CursorPagination(t *testing.T) {
+	lastID := uuid.New()
+
+	mockRepo := &mockProductRepository{
+		mockFindAll: func(ctx context.Context, filter repository.ProductFilter) ([]models.Product, int64, error) {
+			if filter.After != nil {
+				assert.Equal(t, lastID, filter.After.ID)
+				return []models.Product{{ID: uuid.New(), Name: "Produk C", Price: 5000}}, 3, nil
+			}
+
+			assert.Equal(t, 3, filter.Limit)
+			assert.Equal(t, "price", filter.SortField)
+			return []models.Product{
+				{ID: uuid.New(), Name: "Produk A", Price: 1000},
+				{ID: lastID, Name: "Produk B", Price: 2000},
+				{ID: uuid.New(), Name: "Produk C", Price: 5000},
+			}, 3, nil
+		},
+	}
+
+	service := NewProductService(mockRepo, &mockUserRepository{}, false, logging.Discard(), tracing.Noop())
+
+	products, meta, err := service.GetProducts(context.Background(), dto.ProductQuery{Limit: 2, Sort: "price", Order: "asc"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	assert.Len(t, products, 2)
+	assert.Equal(t, int64(3), meta.Total)
+	assert.True(t, meta.HasMore)
+	assert.NotEmpty(t, meta.NextCursor)
+
+	products, meta, err = service.GetProducts(context.Background(), dto.ProductQuery{Limit: 2, Sort: "price", Order: "asc", Cursor: meta.NextCursor})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	assert.Len(t, products, 1)
+	assert.False(t, meta.HasMore)
+	assert.Empty(t, meta.NextCursor)
+}
+
+func TestGetProducts_InvalidQuery(t *testing.T) {
+	service := NewProductService(&mockProductRepository{}, &mockUserRepository{}, false, logging.Discard(), tracing.Noop())
+
+	_, _, err := service.GetProducts(context.Background(), dto.ProductQuery{Sort: "password"})
+	assert.ErrorIs(t, err, ErrInvalidSort)
+
+	_, _, err = service.GetProducts(context.Background(), dto.ProductQuery{Cursor: "not-a-cursor!"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestCreateProduct_RecordsErrorOnSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	service := NewProductService(&mockProductRepository{}, &mockUserRepository{}, false, logging.Discard(), tracing.Tracer(provider))
+
+	err := service.CreateProduct(context.Background(), &models.Product{Name: "Product A", Price: 1000})
+	assert.ErrorIs(t, err, ErrInvalidUserID)
+
+	// span dari pemanggilan yang gagal harus berstatus error
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "ProductService.CreateProduct", spans[0].Name)
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+		assert.Equal(t, ErrInvalidUserID.Error(), spans[0].Status.Description)
+	}
+}
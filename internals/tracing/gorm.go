@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey holds the span of a query in the gorm.DB instance settings.
+const spanKey = "tracing:span"
+
+// InstrumentDB starts a client span for every query of db, a child of the
+// span in the context given to WithContext. The statement is recorded
+// without its parameters.
+func InstrumentDB(db *gorm.DB, provider trace.TracerProvider) error {
+	tracer := Tracer(provider)
+	system := db.Dialector.Name()
+
+	start := func(operation string) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			ctx, span := tracer.Start(WithRequestSpan(db.Statement.Context), "gorm."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("db.system", system),
+					attribute.String("db.operation.name", operation),
+				))
+			db.Statement.Context = ctx
+			db.InstanceSet(spanKey, span)
+		}
+	}
+
+	end := func(db *gorm.DB) {
+		value, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+
+		span.SetAttributes(
+			attribute.String("db.collection.name", db.Statement.Table),
+			attribute.String("db.query.text", db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		End(span, err)
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", start("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", end),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", start("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", end),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", start("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", end),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", start("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", end),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", start("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", end),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", start("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", end),
+	)
+}
@@ -0,0 +1,98 @@
+// Package tracing sets up OpenTelemetry. The server span of a request is
+// started by middlewares.Tracing, services and GORM queries add child spans
+// through Start.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName names the tracers of the application.
+const instrumentationName = "github.com/iamtaufik/golang-vercel-deployment"
+
+// SpanLocal is the local holding the server span of a request. The request
+// context of fasthttp cannot carry the OpenTelemetry context key, so Start
+// looks the span up under this name.
+const SpanLocal = "trace_span"
+
+// Propagator reads and writes W3C traceparent, tracestate and baggage.
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// NewProvider returns the provider exporting to cfg.Exporter, stdout writes
+// to w. Spans are batched, Shutdown flushes the last ones.
+func NewProvider(ctx context.Context, cfg config.TracingConfig, w io.Writer) (*sdktrace.TracerProvider, error) {
+	res := resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case config.TracingStdout:
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, fmt.Errorf("create stdout exporter: %w", err)
+		}
+		exporter = stdout
+	case config.TracingOTLP:
+		var options []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		otlp, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("create otlp exporter: %w", err)
+		}
+		exporter = otlp
+	default:
+		return sdktrace.NewTracerProvider(sdktrace.WithResource(res), sdktrace.WithSampler(sdktrace.NeverSample())), nil
+	}
+
+	return sdktrace.NewTracerProvider(sdktrace.WithResource(res), sdktrace.WithBatcher(exporter)), nil
+}
+
+// Tracer returns the tracer of the application from provider.
+func Tracer(provider trace.TracerProvider) trace.Tracer {
+	return provider.Tracer(instrumentationName)
+}
+
+// Noop returns a tracer that records nothing, for tests.
+func Noop() trace.Tracer {
+	return noop.NewTracerProvider().Tracer(instrumentationName)
+}
+
+// WithRequestSpan returns ctx carrying the server span stored in the
+// SpanLocal of a fasthttp request context, unless ctx already has a span.
+func WithRequestSpan(ctx context.Context) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	if span, ok := ctx.Value(SpanLocal).(trace.Span); ok {
+		return trace.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
+
+// Start starts a child span of the span in ctx, see WithRequestSpan.
+func Start(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(WithRequestSpan(ctx), name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,13 +1,48 @@
 package crypto
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultCost is the bcrypt cost used by HashPassword.
+const DefaultCost = 10
 
 func HashPassword(password string) (string,error)  {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	return HashPasswordWithCost(password, DefaultCost)
+}
+
+func HashPasswordWithCost(password string, cost int) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	return string(bytes), err
 }
 
+// CheckPasswordHash accepts Argon2id and bcrypt hashes.
 func CheckPasswordHash(password string, hashed string) bool {
+	if strings.HasPrefix(hashed, "$argon2id$") {
+		return checkArgon2id(password, hashed)
+	}
+
 	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+
+// GenerateToken returns a random URL safe token of size random bytes.
+func GenerateToken(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// HashToken returns the SHA-256 hex digest stored in place of a token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher hashes passwords into a self describing string: PHC format for
+// Argon2id and the modular crypt format for bcrypt. Every hasher verifies
+// the hashes of both algorithms, so users can log in while their hash is
+// upgraded.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) bool
+	// NeedsRehash reports whether encoded was made with another algorithm
+	// or other parameters than Hash uses.
+	NeedsRehash(encoded string) bool
+}
+
+// Password hashing algorithms accepted by NewHasher.
+const (
+	Argon2id	= "argon2id"
+	Bcrypt		= "bcrypt"
+)
+
+// BcryptMaxLength is the longest password in bytes bcrypt can hash.
+const BcryptMaxLength = 72
+
+// NewHasher returns the hasher of algorithm, bcrypt with bcryptCost or
+// Argon2id with the parameters of argon2.
+func NewHasher(algorithm string, bcryptCost int, argon2 Argon2idHasher) Hasher {
+	if algorithm == Bcrypt {
+		return BcryptHasher{Cost: bcryptCost}
+	}
+
+	return argon2
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	return HashPasswordWithCost(password, h.Cost)
+}
+
+func (h BcryptHasher) Verify(password, encoded string) bool {
+	return CheckPasswordHash(password, encoded)
+}
+
+func (h BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.Cost
+}
+
+const (
+	argon2SaltLength	= 16
+	argon2KeyLength		= 32
+)
+
+var errInvalidArgon2Hash = errors.New("invalid argon2id hash")
+
+// Argon2idHasher makes hashes like
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>, salt and key in unpadded
+// base64. Memory is in KiB.
+type Argon2idHasher struct {
+	Memory		uint32
+	Iterations	uint32
+	Parallelism	uint8
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2idHasher) Verify(password, encoded string) bool {
+	return CheckPasswordHash(password, encoded)
+}
+
+func (h Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params != h || len(key) != argon2KeyLength
+}
+
+func decodeArgon2id(encoded string) (Argon2idHasher, []byte, []byte, error) {
+	var params Argon2idHasher
+
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errInvalidArgon2Hash
+	}
+
+	return params, salt, key, nil
+}
+
+func checkArgon2id(password, encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil || params.Iterations == 0 || params.Parallelism == 0 {
+		return false
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1
+}
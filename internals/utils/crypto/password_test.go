@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testArgon2 = Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2idHasher_HashAndVerify(t *testing.T) {
+	hash, err := testArgon2.Hash("rahasia123")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"))
+	assert.True(t, testArgon2.Verify("rahasia123", hash))
+	assert.False(t, testArgon2.Verify("salah", hash))
+	assert.False(t, testArgon2.NeedsRehash(hash))
+
+	stronger := Argon2idHasher{Memory: 128, Iterations: 1, Parallelism: 1}
+	assert.True(t, stronger.NeedsRehash(hash))
+	assert.True(t, stronger.Verify("rahasia123", hash))
+}
+
+func TestHasher_AcceptsBothAlgorithms(t *testing.T) {
+	bcryptHasher := BcryptHasher{Cost: 4}
+
+	bcryptHash, err := bcryptHasher.Hash("rahasia123")
+	require.NoError(t, err)
+	argonHash, err := testArgon2.Hash("rahasia123")
+	require.NoError(t, err)
+
+	assert.True(t, testArgon2.Verify("rahasia123", bcryptHash))
+	assert.True(t, testArgon2.NeedsRehash(bcryptHash))
+
+	assert.True(t, bcryptHasher.Verify("rahasia123", argonHash))
+	assert.True(t, bcryptHasher.NeedsRehash(argonHash))
+	assert.False(t, bcryptHasher.NeedsRehash(bcryptHash))
+	assert.True(t, BcryptHasher{Cost: 5}.NeedsRehash(bcryptHash))
+}
+
+func TestCheckPasswordHash_MalformedArgon2(t *testing.T) {
+	for _, hash := range []string{
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!$a2V5",
+	} {
+		assert.False(t, CheckPasswordHash("rahasia123", hash), hash)
+	}
+}
+
+func TestNewHasher(t *testing.T) {
+	argon2 := Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+	assert.Equal(t, BcryptHasher{Cost: 4}, NewHasher(Bcrypt, 4, argon2))
+	assert.Equal(t, argon2, NewHasher(Argon2id, 4, argon2))
+}
@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Token types, carried in the token_type claim so a token of one kind is
+// never accepted as another.
+const (
+	TypeAccess	= "access"
+	TypeRefresh	= "refresh"
+	// TypeMFA marks the token returned by a login that still needs the
+	// second factor.
+	TypeMFA		= "mfa_pending"
+)
+
+var (
+	ErrInvalidToken		= errors.New("invalid or expired token")
+	ErrWrongTokenType	= errors.New("wrong token type")
+	ErrInvalidIssuer	= errors.New("invalid issuer")
+	ErrInvalidAudience	= errors.New("invalid audience")
+)
+
+// Claims are the registered claims of RFC 7519 shared by every token, plus
+// the token type. Subject is the user ID.
+type Claims struct {
+	jwt.RegisteredClaims
+	TokenType string `json:"token_type"`
+}
+
+// AccessClaims carry the roles and permissions of the user, so middlewares
+// can authorize without a query.
+type AccessClaims struct {
+	Claims
+	Roles		[]string	`json:"roles"`
+	Permissions	[]string	`json:"permissions"`
+}
+
+// claims is implemented by every claims struct of this package.
+type claims interface {
+	jwt.Claims
+	base() *Claims
+}
+
+func (c *Claims) base() *Claims {
+	return c
+}
+
+// Valid is a no-op: the parser skips its own claims validation and Manager
+// checks the times with leeway, the issuer, the audience and the type.
+func (c *Claims) Valid() error {
+	return nil
+}
+
+// newClaims returns the registered claims of a token of tokenType issued now.
+func (m *Manager) newClaims(tokenType, subject, id string, ttl time.Duration) Claims {
+	now := time.Now()
+
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer: m.issuer,
+			Subject: subject,
+			Audience: jwt.ClaimStrings{m.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt: jwt.NewNumericDate(now),
+			ID: id,
+		},
+		TokenType: tokenType,
+	}
+}
+
+// verify checks the claims of a parsed token. Times are compared with the
+// configured leeway to tolerate clock skew between servers.
+func (m *Manager) verify(c *Claims, tokenType string) error {
+	now := time.Now()
+
+	if c.ExpiresAt == nil || now.After(c.ExpiresAt.Add(m.leeway)) {
+		return ErrInvalidToken
+	}
+	if c.NotBefore != nil && now.Before(c.NotBefore.Add(-m.leeway)) {
+		return ErrInvalidToken
+	}
+	if c.IssuedAt != nil && now.Before(c.IssuedAt.Add(-m.leeway)) {
+		return ErrInvalidToken
+	}
+
+	if c.Issuer != m.issuer {
+		return ErrInvalidIssuer
+	}
+	if !slices.Contains(c.Audience, m.audience) {
+		return ErrInvalidAudience
+	}
+	if c.Subject == "" {
+		return ErrInvalidToken
+	}
+	if c.TokenType != tokenType {
+		return ErrWrongTokenType
+	}
+
+	return nil
+}
+
+// parse verifies the signature of tokenStr with keyFunc and its claims.
+func (m *Manager) parse(tokenStr, tokenType string, out claims, keyFunc jwt.Keyfunc) error {
+	parser := jwt.Parser{SkipClaimsValidation: true}
+
+	token, err := parser.ParseWithClaims(tokenStr, out, keyFunc)
+	if err != nil || !token.Valid {
+		return ErrInvalidToken
+	}
+
+	return m.verify(out.base(), tokenType)
+}
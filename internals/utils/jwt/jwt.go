@@ -2,53 +2,248 @@ package jwt
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
 )
 
-func GenerateAccessToken(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Minute * 2).Unix(),
+// Manager signs and validates the access and refresh tokens with the
+// secrets, keys and lifetimes from config.JWTConfig. Access tokens use
+// HS256 with the shared secret or, when signing is set, the asymmetric key
+// named by the kid header. Refresh and MFA tokens are only read by this
+// server and always use HS256 with the refresh secret, so no verifier of
+// access tokens accepts them.
+type Manager struct {
+	secret			[]byte
+	refreshSecret	[]byte
+	signing			*key
+	keys			map[string]*key
+	issuer			string
+	audience		string
+	leeway			time.Duration
+	accessTTL		time.Duration
+	refreshTTL		time.Duration
+	mfaTTL			time.Duration
+}
+
+// NewManager loads the keys of cfg. It fails when the asymmetric keys can
+// not be read or the active key does not match cfg.Algorithm.
+func NewManager(cfg config.JWTConfig) (*Manager, error) {
+	m := &Manager{
+		secret: []byte(cfg.Secret),
+		refreshSecret: []byte(cfg.RefreshSecret),
+		keys: map[string]*key{},
+		issuer: cfg.Issuer,
+		audience: cfg.Audience,
+		leeway: cfg.Leeway,
+		accessTTL: cfg.AccessTTL,
+		refreshTTL: cfg.RefreshTTL,
+		mfaTTL: cfg.MFATTL,
+	}
+
+	if cfg.Algorithm == "" || cfg.Algorithm == config.AlgorithmHS256 {
+		return m, nil
+	}
+
+	keys, err := loadKeyDir(cfg.KeysDir)
+	if err != nil {
+		return nil, err
+	}
+	m.keys = keys
+
+	if cfg.SigningKey != "" {
+		signing, err := parseKey(cfg.ActiveKeyID, []byte(cfg.SigningKey))
+		if err != nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY: %w", err)
+		}
+		m.keys[signing.id] = signing
+	}
+
+	activeID := cfg.ActiveKeyID
+	if activeID == "" {
+		activeID, err = onlyPrivateKey(m.keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signing, ok := m.keys[activeID]
+	if !ok || signing.private == nil {
+		return nil, fmt.Errorf("no private key with kid %q", activeID)
+	}
+	if signing.method.Alg() != cfg.Algorithm {
+		return nil, fmt.Errorf("key %q is a %s key, not %s", activeID, signing.method.Alg(), cfg.Algorithm)
+	}
+	m.signing = signing
+
+	return m, nil
+}
+
+// onlyPrivateKey returns the kid of the single private key, so a deployment
+// with one key does not have to name it.
+func onlyPrivateKey(keys map[string]*key) (string, error) {
+	var ids []string
+	for id, k := range keys {
+		if k.private != nil {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) != 1 {
+		return "", fmt.Errorf("found %d private keys, set JWT_ACTIVE_KEY_ID", len(ids))
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return ids[0], nil
 }
 
-func GenerateRefreshToken(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 7 * 60).Unix(), // 7 hari
+// JWKS returns the public keys that verify access tokens. It is empty with
+// HS256, whose secret must never be published.
+func (m *Manager) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range m.keys {
+		set.Keys = append(set.Keys, k.jwk())
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_REFRESH_SECRET")))
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
 }
 
-func ValidateRefreshToken(tokenStr string) (string, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+// sign signs claims with the active key, or with the secret for HS256.
+func (m *Manager) sign(claims jwt.Claims) (string, error) {
+	if m.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	}
+
+	token := jwt.NewWithClaims(m.signing.method, claims)
+	token.Header["kid"] = m.signing.id
+	return token.SignedString(m.signing.private)
+}
+
+// verificationKey is the jwt.Keyfunc of tokens created by sign. The
+// algorithm must match the key, so a public key can never be used as an
+// HMAC secret.
+func (m *Manager) verificationKey(t *jwt.Token) (interface{}, error) {
+	if m.signing == nil {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(os.Getenv("JWT_REFRESH_SECRET")), nil
-	})
-
-	if err != nil || !token.Valid {
-		return "", errors.New("invalid or expired refresh token")
+		return m.secret, nil
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
+	kid, _ := t.Header["kid"].(string)
+	k, ok := m.keys[kid]
 	if !ok {
-		return "", errors.New("could not parse claims")
+		return nil, fmt.Errorf("unknown kid %q", kid)
 	}
 
-	userID, ok := claims["user_id"].(string)
-	if !ok {
-		return "", errors.New("invalid user_id")
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+
+	return k.public, nil
+}
+
+// Check signs and validates a throwaway access token, which proves the
+// signing key is loaded and matches its verification key.
+func (m *Manager) Check() error {
+	if m.signing == nil && len(m.secret) == 0 {
+		return errors.New("no access token secret")
+	}
+	if len(m.refreshSecret) == 0 {
+		return errors.New("no refresh token secret")
+	}
+
+	token, err := m.GenerateAccessToken(uuid.Nil.String(), nil, nil)
+	if err != nil {
+		return fmt.Errorf("sign: %w", err)
+	}
+
+	if _, err := m.ValidateAccessToken(token); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+// RefreshTTL is how long a refresh token stays valid.
+func (m *Manager) RefreshTTL() time.Duration {
+	return m.refreshTTL
+}
+
+// GenerateAccessToken signs an access token carrying the roles and
+// permissions of the user.
+func (m *Manager) GenerateAccessToken(userID string, roles, permissions []string) (string, error) {
+	claims := &AccessClaims{
+		Claims: m.newClaims(TypeAccess, userID, uuid.NewString(), m.accessTTL),
+		Roles: roles,
+		Permissions: permissions,
+	}
+
+	return m.sign(claims)
+}
+
+// ValidateAccessToken returns the claims of a valid access token.
+func (m *Manager) ValidateAccessToken(tokenStr string) (*AccessClaims, error) {
+	var claims AccessClaims
+	if err := m.parse(tokenStr, TypeAccess, &claims, m.verificationKey); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+// GenerateMFAToken signs the short lived token that proves the password of
+// the user was checked, to be exchanged for real tokens with a TOTP code.
+// It is not signed with the published keys, so only this server accepts it.
+func (m *Manager) GenerateMFAToken(userID string) (string, error) {
+	claims := m.newClaims(TypeMFA, userID, uuid.NewString(), m.mfaTTL)
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, &claims).SignedString(m.refreshSecret)
+}
+
+// ValidateMFAToken returns the user ID and token ID of a valid MFA token.
+// The token ID lets callers accept each token only once.
+func (m *Manager) ValidateMFAToken(tokenStr string) (string, string, error) {
+	var claims Claims
+	if err := m.parse(tokenStr, TypeMFA, &claims, m.serverKey); err != nil {
+		return "", "", err
 	}
 
-	return userID, nil
-}
\ No newline at end of file
+	return claims.Subject, claims.ID, nil
+}
+
+// GenerateRefreshToken signs a refresh token whose jti is tokenID, the ID of
+// the server side record that tracks its rotation.
+func (m *Manager) GenerateRefreshToken(userID, tokenID string) (string, error) {
+	claims := m.newClaims(TypeRefresh, userID, tokenID, m.refreshTTL)
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, &claims).SignedString(m.refreshSecret)
+}
+
+// ValidateRefreshToken returns the user ID and token ID of a refresh token.
+func (m *Manager) ValidateRefreshToken(tokenStr string) (string, string, error) {
+	var claims Claims
+	if err := m.parse(tokenStr, TypeRefresh, &claims, m.serverKey); err != nil {
+		return "", "", err
+	}
+
+	if claims.ID == "" {
+		return "", "", errors.New("invalid jti")
+	}
+
+	return claims.Subject, claims.ID, nil
+}
+
+// serverKey is the jwt.Keyfunc of the refresh and MFA tokens, which only
+// this server signs and reads.
+func (m *Manager) serverKey(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, errors.New("invalid signing method")
+	}
+	return m.refreshSecret, nil
+}
@@ -0,0 +1,219 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() config.JWTConfig {
+	cfg := config.Default().JWT
+	cfg.Secret = "test-access-secret-with-32-characters"
+	cfg.RefreshSecret = "test-refresh-secret-with-32-characters"
+	return cfg
+}
+
+func writePrivateKey(t *testing.T, dir, kid string, private any) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), pemBytes, 0o600))
+}
+
+func writePublicKey(t *testing.T, dir, kid string, public any) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), pemBytes, 0o600))
+}
+
+func TestManager_HS256(t *testing.T) {
+	m, err := NewManager(testConfig())
+	require.NoError(t, err)
+
+	token, err := m.GenerateAccessToken("user-1", []string{"seller"}, nil)
+	require.NoError(t, err)
+
+	claims, err := m.ValidateAccessToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, []string{"seller"}, claims.Roles)
+	assert.Empty(t, m.JWKS().Keys)
+	assert.NoError(t, m.Check())
+
+	cfg := testConfig()
+	cfg.Secret = ""
+	m, err = NewManager(cfg)
+	require.NoError(t, err)
+	assert.ErrorContains(t, m.Check(), "no access token secret")
+}
+
+func TestManager_RotatedKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writePrivateKey(t, dir, "2024-01", oldKey)
+
+	cfg := testConfig()
+	cfg.Algorithm = config.AlgorithmRS256
+	cfg.KeysDir = dir
+
+	oldManager, err := NewManager(cfg)
+	require.NoError(t, err)
+	oldToken, err := oldManager.GenerateAccessToken("user-1", nil, nil)
+	require.NoError(t, err)
+
+	// Rotate: the new Ed25519 key signs, the old key only verifies.
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	writePrivateKey(t, dir, "2024-06", newKey)
+	writePublicKey(t, dir, "2024-01", &oldKey.PublicKey)
+
+	cfg.Algorithm = config.AlgorithmEdDSA
+	cfg.ActiveKeyID = "2024-06"
+	m, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	newToken, err := m.GenerateAccessToken("user-2", nil, nil)
+	require.NoError(t, err)
+
+	parsed, _, _ := new(jwt.Parser).ParseUnverified(newToken, jwt.MapClaims{})
+	assert.Equal(t, "2024-06", parsed.Header["kid"])
+	assert.Equal(t, "EdDSA", parsed.Header["alg"])
+
+	_, err = m.ValidateAccessToken(oldToken)
+	assert.NoError(t, err)
+	_, err = m.ValidateAccessToken(newToken)
+	assert.NoError(t, err)
+
+	keys := m.JWKS().Keys
+	require.Len(t, keys, 2)
+	assert.Equal(t, JWK{Kty: "RSA", Kid: "2024-01", Use: "sig", Alg: "RS256", N: keys[0].N, E: "AQAB"}, keys[0])
+	assert.Equal(t, "OKP", keys[1].Kty)
+	assert.Equal(t, "Ed25519", keys[1].Crv)
+}
+
+func TestManager_RejectsUnknownKeyAndHMAC(t *testing.T) {
+	dir := t.TempDir()
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	writePrivateKey(t, dir, "main", private)
+
+	cfg := testConfig()
+	cfg.Algorithm = config.AlgorithmEdDSA
+	cfg.KeysDir = dir
+
+	m, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	// A token signed with the shared secret must not pass once keys are used.
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "user-1",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+	hmacToken.Header["kid"] = "main"
+	signed, _ := hmacToken.SignedString([]byte(cfg.Secret))
+
+	_, err = m.ValidateAccessToken(signed)
+	assert.Error(t, err)
+
+	cfg.Algorithm = config.AlgorithmRS256
+	_, err = NewManager(cfg)
+	assert.ErrorContains(t, err, "not RS256")
+}
+
+func TestManager_MFATokenNotSignedWithPublishedKey(t *testing.T) {
+	dir := t.TempDir()
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	writePrivateKey(t, dir, "main", private)
+
+	cfg := testConfig()
+	cfg.Algorithm = config.AlgorithmEdDSA
+	cfg.KeysDir = dir
+
+	m, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	mfa, err := m.GenerateMFAToken("user-1")
+	require.NoError(t, err)
+
+	userID, tokenID, err := m.ValidateMFAToken(mfa)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	assert.NotEmpty(t, tokenID)
+
+	// A verifier using the JWKS must reject it, even though the issuer and
+	// audience match.
+	_, err = jwt.Parse(mfa, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodEd25519); !ok {
+			return nil, errors.New("invalid signing method")
+		}
+		return public, nil
+	})
+	assert.Error(t, err)
+}
+
+func TestManager_RejectsWrongTypeIssuerAndAudience(t *testing.T) {
+	cfg := testConfig()
+	cfg.RefreshSecret = cfg.Secret
+	m, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	access, _ := m.GenerateAccessToken("user-1", nil, nil)
+	refresh, _ := m.GenerateRefreshToken("user-1", "token-1")
+	mfa, _ := m.GenerateMFAToken("user-1")
+
+	// Both kinds share a secret here, only the type claim tells them apart.
+	_, _, err = m.ValidateRefreshToken(access)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+	_, err = m.ValidateAccessToken(refresh)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+	_, err = m.ValidateAccessToken(mfa)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+
+	userID, tokenID, err := m.ValidateRefreshToken(refresh)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	assert.Equal(t, "token-1", tokenID)
+
+	other := cfg
+	other.Issuer = "someone-else"
+	otherManager, _ := NewManager(other)
+	_, err = otherManager.ValidateAccessToken(access)
+	assert.ErrorIs(t, err, ErrInvalidIssuer)
+
+	other = cfg
+	other.Audience = "another-api"
+	otherManager, _ = NewManager(other)
+	_, err = otherManager.ValidateAccessToken(access)
+	assert.ErrorIs(t, err, ErrInvalidAudience)
+}
+
+func TestManager_Leeway(t *testing.T) {
+	cfg := testConfig()
+	m, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	expired := func(ago time.Duration) string {
+		claims := m.newClaims(TypeAccess, "user-1", "id", time.Minute)
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-ago))
+		token, _ := m.sign(&AccessClaims{Claims: claims})
+		return token
+	}
+
+	_, err = m.ValidateAccessToken(expired(cfg.Leeway / 2))
+	assert.NoError(t, err)
+
+	_, err = m.ValidateAccessToken(expired(2 * cfg.Leeway))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
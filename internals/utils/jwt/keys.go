@@ -0,0 +1,137 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// key is an asymmetric key identified by the kid header. private is nil for
+// keys that are only kept to verify tokens signed before a rotation.
+type key struct {
+	id		string
+	method	jwt.SigningMethod
+	private	crypto.Signer
+	public	crypto.PublicKey
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517.
+type JWK struct {
+	Kty	string	`json:"kty"`
+	Kid	string	`json:"kid"`
+	Use	string	`json:"use"`
+	Alg	string	`json:"alg"`
+	N	string	`json:"n,omitempty"`
+	E	string	`json:"e,omitempty"`
+	Crv	string	`json:"crv,omitempty"`
+	X	string	`json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *key) jwk() JWK {
+	jwk := JWK{Kid: k.id, Use: "sig", Alg: k.method.Alg()}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
+
+// loadKeyDir reads every .pem file of dir. The file name without extension
+// is the kid. Private keys can sign, public keys only verify.
+func loadKeyDir(dir string) (map[string]*key, error) {
+	keys := map[string]*key{}
+	if dir == "" {
+		return keys, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read key %s: %w", path, err)
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		parsed, err := parseKey(id, content)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", path, err)
+		}
+
+		keys[id] = parsed
+	}
+
+	return keys, nil
+}
+
+// parseKey reads a PEM encoded RSA or Ed25519 key, private (PKCS #1 or
+// PKCS #8) or public (PKIX or PKCS #1).
+func parseKey(id string, content []byte) (*key, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	k := &key{id: id}
+	switch value := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.method, k.private, k.public = jwt.SigningMethodRS256, value, &value.PublicKey
+	case ed25519.PrivateKey:
+		k.method, k.private, k.public = jwt.SigningMethodEdDSA, value, value.Public()
+	case *rsa.PublicKey:
+		k.method, k.public = jwt.SigningMethodRS256, value
+	case ed25519.PublicKey:
+		k.method, k.public = jwt.SigningMethodEdDSA, value
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+
+	return k, nil
+}
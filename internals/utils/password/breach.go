@@ -0,0 +1,73 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// prefixLength is the length of the hash prefixes the list is indexed by,
+// the same as the Pwned Passwords range API.
+const prefixLength = 5
+
+// BreachList holds the SHA-1 hashes of breached passwords, grouped by their
+// first five hex characters like the Pwned Passwords range files, so a
+// lookup only searches one small sorted group.
+type BreachList struct {
+	ranges map[string][]string
+}
+
+// LoadBreachList reads one upper or lower case SHA-1 hex hash per line,
+// optionally followed by ":count" as in the Pwned Passwords downloads. Empty
+// lines and lines starting with # are skipped.
+func LoadBreachList(path string) (*BreachList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breach list: %w", err)
+	}
+	defer file.Close()
+
+	list := &BreachList{ranges: map[string][]string{}}
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("breach list %s line %d: not a SHA-1 hash", path, line)
+		}
+
+		prefix := hash[:prefixLength]
+		list.ranges[prefix] = append(list.ranges[prefix], hash[prefixLength:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read breach list: %w", err)
+	}
+
+	for _, suffixes := range list.ranges {
+		sort.Strings(suffixes)
+	}
+
+	return list, nil
+}
+
+// Contains reports whether password is in the list.
+func (l *BreachList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes := l.ranges[hash[:prefixLength]]
+	suffix := hash[prefixLength:]
+	i := sort.SearchStrings(suffixes, suffix)
+
+	return i < len(suffixes) && suffixes[i] == suffix
+}
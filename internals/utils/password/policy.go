@@ -0,0 +1,188 @@
+// Package password decides whether a new password is good enough: long
+// enough, not built from the user's own name or email, hard enough to guess
+// and not in a list of breached passwords.
+package password
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/utils/crypto"
+)
+
+// Rules reported in a Violation.
+const (
+	RuleMinLength	= "min_length"
+	RuleMaxLength	= "max_length"
+	RuleBanned		= "banned_substring"
+	RuleEntropy		= "min_entropy"
+	RuleBreached	= "breached"
+)
+
+// minBannedLength keeps short names like "Al" from banning half of all
+// passwords.
+const minBannedLength = 3
+
+// Violation is one rule a password breaks, Message tells the user what to
+// change.
+type Violation struct {
+	Rule	string
+	Message	string
+}
+
+type Policy struct {
+	MinLength	int
+	// MaxBytes limits the length in bytes, 0 means no limit.
+	MaxBytes	int
+	// MinEntropy is the least number of bits Entropy must estimate.
+	MinEntropy	float64
+	// Breached may be nil, nothing is then considered breached.
+	Breached	*BreachList
+}
+
+// NewPolicy returns the configured policy, loading the breach list if one is
+// set.
+func NewPolicy(cfg config.PasswordConfig) (*Policy, error) {
+	policy := &Policy{MinLength: cfg.MinLength, MinEntropy: float64(cfg.MinEntropy)}
+	if cfg.Algorithm == config.PasswordBcrypt {
+		policy.MaxBytes = crypto.BcryptMaxLength
+	}
+
+	if cfg.BreachList != "" {
+		list, err := LoadBreachList(cfg.BreachList)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = list
+	}
+
+	return policy, nil
+}
+
+// Check returns every rule password breaks. banned are strings the password
+// must not contain, such as the user's name and email address.
+func (p *Policy) Check(password string, banned ...string) []Violation {
+	var violations []Violation
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, Violation{
+			Rule: RuleMinLength,
+			Message: fmt.Sprintf("must be at least %d characters", p.MinLength),
+		})
+	}
+
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violations = append(violations, Violation{
+			Rule: RuleMaxLength,
+			Message: fmt.Sprintf("must be at most %d bytes", p.MaxBytes),
+		})
+	}
+
+	lower := strings.ToLower(password)
+	for _, word := range bannedWords(banned) {
+		if strings.Contains(lower, word) {
+			violations = append(violations, Violation{
+				Rule: RuleBanned,
+				Message: fmt.Sprintf("must not contain %q from your name or email", word),
+			})
+		}
+	}
+
+	if Entropy(password) < p.MinEntropy {
+		violations = append(violations, Violation{
+			Rule: RuleEntropy,
+			Message: "is too easy to guess, use a longer password or mix letters, digits and symbols",
+		})
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, Violation{
+			Rule: RuleBreached,
+			Message: "appeared in a data breach, choose another one",
+		})
+	}
+
+	return violations
+}
+
+// bannedWords splits names and email addresses into the lower case words a
+// password must not contain, e.g. "Taufik Hidayat" and "taufik@dev.com"
+// give taufik, hidayat and dev.
+func bannedWords(values []string) []string {
+	var words []string
+	seen := map[string]bool{}
+
+	for _, value := range values {
+		fields := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+
+		for _, field := range fields {
+			if utf8.RuneCountInString(field) < minBannedLength || seen[field] || commonEmailWords[field] {
+				continue
+			}
+			seen[field] = true
+			words = append(words, field)
+		}
+	}
+
+	return words
+}
+
+// commonEmailWords are parts of email addresses that say nothing about the
+// user.
+var commonEmailWords = map[string]bool{
+	"com": true, "net": true, "org": true, "gmail": true, "yahoo": true, "outlook": true, "hotmail": true,
+}
+
+// Entropy estimates the bits of a password from the character classes it
+// uses. A character repeating the one before it or continuing a run such as
+// "abc" or "321" adds a single bit.
+func Entropy(password string) float64 {
+	pool := 0
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	for _, class := range []struct {
+		used	bool
+		size	int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	perChar := math.Log2(float64(pool))
+
+	var bits float64
+	var prev rune
+	for i, r := range []rune(password) {
+		if i > 0 && (r == prev || r == prev+1 || r == prev-1) {
+			bits++
+		} else {
+			bits += perChar
+		}
+		prev = r
+	}
+
+	return bits
+}
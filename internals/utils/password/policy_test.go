@@ -0,0 +1,83 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rules(violations []Violation) []string {
+	var names []string
+	for _, violation := range violations {
+		names = append(names, violation.Rule)
+	}
+	return names
+}
+
+func TestEntropy(t *testing.T) {
+	assert.Zero(t, Entropy(""))
+	// Runs and repeats add one bit per character.
+	assert.Less(t, Entropy("1234567890"), 20.0)
+	assert.Less(t, Entropy("aaaaaaaaaaaa"), 20.0)
+	assert.Greater(t, Entropy("kopi-susu-gula-aren"), 60.0)
+	assert.Greater(t, Entropy("Tr0ub4dor&3"), Entropy("troubadour"))
+}
+
+func TestPolicy_Check(t *testing.T) {
+	policy := &Policy{MinLength: 8, MinEntropy: 35}
+
+	assert.Empty(t, policy.Check("kopi-susu-gula-aren", "Taufik Hidayat", "taufik@dev.com"))
+
+	assert.Equal(t, []string{RuleMinLength, RuleEntropy}, rules(policy.Check("abc", "Taufik", "taufik@dev.com")))
+	assert.Equal(t, []string{RuleBanned}, rules(policy.Check("Hidayat-kopi-susu", "Taufik Hidayat", "taufik@dev.com")))
+	assert.Equal(t, []string{RuleBanned}, rules(policy.Check("kopi-dev-susu-gula", "Taufik", "taufik@dev.com")))
+
+	// Short words and common email domains are not banned.
+	assert.Empty(t, policy.Check("kopi-al-gmail-susu", "Al", "al@gmail.com"))
+}
+
+func TestNewPolicy_BcryptLimitsLength(t *testing.T) {
+	long := strings.Repeat("kopi-susu-gula-aren-", 5)
+
+	policy, err := NewPolicy(config.PasswordConfig{Algorithm: config.PasswordArgon2id, MinLength: 8})
+	require.NoError(t, err)
+	assert.Empty(t, policy.Check(long))
+
+	policy, err = NewPolicy(config.PasswordConfig{Algorithm: config.PasswordBcrypt, MinLength: 8})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RuleMaxLength}, rules(policy.Check(long)))
+	assert.Empty(t, policy.Check(long[:72]))
+}
+
+func TestBreachList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	content := "# SHA-1 of breached passwords\n" +
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n" + // password
+		"7c4a8d09ca3762af61e59520943dc26494f8941b\n" + // 123456
+		"\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	list, err := LoadBreachList(path)
+	require.NoError(t, err)
+
+	assert.True(t, list.Contains("password"))
+	assert.True(t, list.Contains("123456"))
+	assert.False(t, list.Contains("kopi-susu-gula-aren"))
+
+	policy := &Policy{MinLength: 6, Breached: list}
+	assert.Equal(t, []string{RuleBreached}, rules(policy.Check("password")))
+}
+
+func TestLoadBreachList_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte("not-a-hash\n"), 0o600))
+
+	_, err := LoadBreachList(path)
+	assert.ErrorContains(t, err, "line 1")
+}
@@ -0,0 +1,114 @@
+// Package ratelimit implements the token bucket and sliding window
+// algorithms over a small State, so any store able to update one State
+// atomically can hold the counters.
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+)
+
+// State is what a store keeps for one key. A zero State is a key that has
+// not been seen. For a token bucket Value is the tokens left at At, for a
+// sliding window Value and Previous are the requests counted in the window
+// starting at At and in the one before.
+type State struct {
+	Value		float64
+	Previous	float64
+	At			time.Time
+	// ExpiresAt is when the state is as good as a zero one and may be
+	// dropped by the store.
+	ExpiresAt	time.Time
+}
+
+// Result is the outcome of one request, with what the RateLimit headers
+// report.
+type Result struct {
+	Allowed		bool
+	Limit		int
+	Remaining	int
+	// Reset is the time until the full limit is available again.
+	Reset		time.Duration
+	// RetryAfter is the time until a denied request would be allowed.
+	RetryAfter	time.Duration
+}
+
+// Take counts one request against state under policy and updates state.
+func Take(policy config.RateLimitPolicy, state *State, now time.Time) Result {
+	if policy.Algorithm == config.RateLimitSlidingWindow {
+		return slidingWindow(policy, state, now)
+	}
+	return tokenBucket(policy, state, now)
+}
+
+func tokenBucket(policy config.RateLimitPolicy, state *State, now time.Time) Result {
+	capacity := float64(policy.Limit)
+	perSecond := capacity / policy.Window.Seconds()
+
+	tokens := capacity
+	if !state.At.IsZero() {
+		tokens = math.Min(capacity, state.Value+now.Sub(state.At).Seconds()*perSecond)
+	}
+
+	result := Result{Limit: policy.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / perSecond)
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = seconds((capacity - tokens) / perSecond)
+
+	state.Value = tokens
+	state.At = now
+	state.ExpiresAt = now.Add(result.Reset)
+
+	return result
+}
+
+// slidingWindow counts requests in fixed windows and weighs the previous
+// window by how much of it still overlaps the last Window, which
+// approximates a true sliding log without storing every request.
+func slidingWindow(policy config.RateLimitPolicy, state *State, now time.Time) Result {
+	start := now.Truncate(policy.Window)
+	if !state.At.Equal(start) {
+		if state.At.Add(policy.Window).Equal(start) {
+			state.Previous = state.Value
+		} else {
+			state.Previous = 0
+		}
+		state.Value = 0
+		state.At = start
+	}
+
+	elapsed := now.Sub(start)
+	limit := float64(policy.Limit)
+	count := state.Previous*(1-elapsed.Seconds()/policy.Window.Seconds()) + state.Value
+
+	result := Result{Limit: policy.Limit, Reset: policy.Window - elapsed}
+	if count+1 <= limit {
+		state.Value++
+		count++
+		result.Allowed = true
+	} else if state.Value+1 > limit || state.Previous == 0 {
+		result.RetryAfter = policy.Window - elapsed
+	} else {
+		// The previous window weighs little enough once
+		// Previous*(1-t/Window) + Value + 1 <= Limit.
+		allowedAt := seconds(policy.Window.Seconds() * (1 - (limit-state.Value-1)/state.Previous))
+		result.RetryAfter = allowedAt - elapsed
+	}
+
+	result.Remaining = max(0, policy.Limit-int(math.Ceil(count)))
+	state.ExpiresAt = start.Add(2 * policy.Window)
+
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
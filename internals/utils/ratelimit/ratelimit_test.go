@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestTokenBucket_BurstThenRefill(t *testing.T) {
+	policy := config.RateLimitPolicy{Algorithm: config.RateLimitTokenBucket, Limit: 3, Window: 3 * time.Second}
+	var state State
+
+	for i := 2; i >= 0; i-- {
+		result := Take(policy, &state, start)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := Take(policy, &state, start)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// One token is back after a second.
+	result = Take(policy, &state, start.Add(time.Second))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// The bucket never holds more than Limit tokens.
+	result = Take(policy, &state, start.Add(time.Hour))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestSlidingWindow_WeighsPreviousWindow(t *testing.T) {
+	policy := config.RateLimitPolicy{Algorithm: config.RateLimitSlidingWindow, Limit: 4, Window: time.Minute}
+	var state State
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, Take(policy, &state, start.Add(30*time.Second)).Allowed)
+	}
+
+	result := Take(policy, &state, start.Add(30*time.Second))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 30*time.Second, result.RetryAfter)
+	assert.Equal(t, 0, result.Remaining)
+
+	// A quarter into the next window the previous 4 requests still count
+	// for 3, so one request is allowed and the next has to wait.
+	assert.True(t, Take(policy, &state, start.Add(75*time.Second)).Allowed)
+
+	result = Take(policy, &state, start.Add(75*time.Second))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 15*time.Second, result.RetryAfter)
+
+	assert.True(t, Take(policy, &state, start.Add(90*time.Second)).Allowed)
+
+	// Two windows later nothing is left of the old requests.
+	state = State{Value: 4, At: start}
+	result = Take(policy, &state, start.Add(150*time.Second))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 3, result.Remaining)
+}
@@ -0,0 +1,114 @@
+// Package totp implements RFC 6238 time based one time passwords with the
+// parameters every authenticator app supports: HMAC-SHA1, 6 digits and a 30
+// second period.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits	= 6
+	Period	= 30 * time.Second
+	// Skew is the number of periods accepted before and after the current
+	// one, to allow for clock drift between server and phone.
+	Skew	= 1
+
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32 encoded secret.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI returns the otpauth URI shown as a QR code during enrollment.
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Code returns the code of secret for the period containing t.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return code(key, counter(t)), nil
+}
+
+// Validate reports whether code matches secret at t, within Skew periods,
+// and returns the counter of the period it matched. Periods at or before
+// last are skipped: callers store the returned counter and pass it as last
+// next time, so a code can not be replayed while it is still valid.
+func Validate(secret, code string, t time.Time, last uint64) (uint64, bool) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != Digits {
+		return 0, false
+	}
+
+	current := counter(t)
+	for offset := -Skew; offset <= Skew; offset++ {
+		candidate := uint64(int64(current) + int64(offset))
+		if candidate <= last {
+			continue
+		}
+		expected := codeAt(key, current, offset)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return candidate, true
+		}
+	}
+
+	return 0, false
+}
+
+func counter(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(Period.Seconds()))
+}
+
+func codeAt(key []byte, current uint64, offset int) string {
+	return code(key, uint64(int64(current)+int64(offset)))
+}
+
+// code is the HOTP value of RFC 4226 for counter.
+func code(key []byte, counter uint64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%modulo)
+}
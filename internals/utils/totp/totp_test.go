@@ -0,0 +1,60 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfc6238Secret is the SHA1 test key of RFC 6238, appendix B.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:				"287082",
+		1111111109:		"081804",
+		1111111111:		"050471",
+		1234567890:		"005924",
+		2000000000:		"279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := Code(rfc6238Secret, time.Unix(unix, 0))
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	code, _ := Code(rfc6238Secret, now)
+
+	_, ok := Validate(rfc6238Secret, code, now, 0)
+	assert.True(t, ok)
+	_, ok = Validate(rfc6238Secret, code, now.Add(Period), 0)
+	assert.True(t, ok)
+	_, ok = Validate(rfc6238Secret, code, now.Add(3*Period), 0)
+	assert.False(t, ok)
+	_, ok = Validate(rfc6238Secret, "12345", now, 0)
+	assert.False(t, ok)
+}
+
+func TestValidate_Replay(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	code, _ := Code(rfc6238Secret, now)
+
+	counter, ok := Validate(rfc6238Secret, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1111111109/30), counter)
+
+	// The same code is still inside the skew window, but already used.
+	_, ok = Validate(rfc6238Secret, code, now.Add(Period), counter)
+	assert.False(t, ok)
+
+	next, _ := Code(rfc6238Secret, now.Add(Period))
+	_, ok = Validate(rfc6238Secret, next, now.Add(Period), counter)
+	assert.True(t, ok)
+}
@@ -1,62 +1,70 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
-
-	// Import your Vercel function's package
-	// The path here depends on your project structure.
-	// If api/index.go is at the root, it might be "your-module-name/api"
-	// If your go.mod is at the root and api/index.go is there, it's just "api"
-	// For this example, let's assume your go.mod is at the root and
-	// your Vercel function is in `api/index.go` with package `handler`.
-	// The import path will be like this:
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/db"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/handlers"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/repository"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/routes"
-	"github.com/iamtaufik/golang-vercel-deployment/internals/services"
-	"github.com/joho/godotenv"
+	"os/signal"
+	"syscall"
+
+	"github.com/iamtaufik/golang-vercel-deployment/internals/app"
+	"github.com/iamtaufik/golang-vercel-deployment/internals/config"
 )
 
-func init(){
-	err := godotenv.Load(".env") 
+func main() {
+	cfg, err := config.Load()
 	if err != nil {
-		fmt.Println("Failed to load .env file")
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
 	}
 }
 
-func main() {
-	app := fiber.New()
-	db := db.ConnectDB()
+// run serves until SIGINT or SIGTERM, then stops accepting connections,
+// waits up to cfg.ShutdownTimeout for the requests in flight and closes the
+// database. A second signal kills the process right away.
+func run(cfg *config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	conn, err := app.Connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			conn.Logger.Error("close database", "error", err.Error())
+		}
+	}()
+
+	server, err := app.New(conn)
+	if err != nil {
+		return err
 	}
 
-	
-	uRepository := repository.NewUserRepository(db)
-	aService 	:= services.NewAuthService(uRepository)
-	aHandler	:= handlers.NewAuthService(aService)
-	
-	pRepository := repository.NewProductRepository(db)
-	pService 	:= services.NewProductService(pRepository, uRepository)
-	pHandler	:= handlers.NewProductHandler(pService)
-	
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     os.Getenv("CLIENT_URL"), // Your Vue development server origin
-		AllowCredentials: true,
-		// AllowHeaders: "Origin, Content-Type, Accept, Authorization", // Good to explicitly allow headers if you send them
-	}))
-
-	routes.RegisterRoutes(app, &routes.RouteConfig{
-		ProductHandler: pHandler,
-		AuthHandler: aHandler,
-	})
-
-	log.Fatal(app.Listen(":" + port))
-}
\ No newline at end of file
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- server.Listen(":" + cfg.Port)
+	}()
+
+	select {
+	case err := <-listenErr:
+		return fmt.Errorf("listen: %w", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	conn.Logger.Info("shutting down", "timeout", cfg.ShutdownTimeout.String())
+	if err := server.ShutdownWithTimeout(cfg.ShutdownTimeout); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+
+	if err := <-listenErr; err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	conn.Logger.Info("server stopped")
+	return nil
+}